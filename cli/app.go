@@ -30,11 +30,12 @@ var appShellCmd = &cobra.Command{
 }
 
 var appBuildCmd = &cobra.Command{
-	Use:   "build",
+	Use:   "build [--dev]",
 	Short: "Run build hook for application.",
 	Run: func(cmd *cobra.Command, args []string) {
 		proj, err := getProject()
 		handleError(err)
+		proj.ComposerDev = cmd.PersistentFlags().Lookup("dev").Value.String() == "true"
 		app := appCmdSelectApp(proj)
 		handleError(proj.Build(app))
 	},
@@ -63,16 +64,18 @@ var appPostDeployCmd = &cobra.Command{
 }
 
 var appInstallDepsCmd = &cobra.Command{
-	Use:     "install-deps",
+	Use:     "install-deps [--update]",
 	Short:   "Install dependencies for application.",
 	Aliases: []string{"install-dependencies", "id", "deps"},
 	Run: func(cmd *cobra.Command, args []string) {
 		proj, err := getProject()
 		handleError(err)
+		proj.UpdateDeps = cmd.PersistentFlags().Lookup("update").Value.String() == "true"
 		app := appCmdSelectApp(proj)
 		handleError(proj.DepPHPComposerInstall(app))
 		handleError(proj.DepNodeNpmInstall(app))
 		handleError(proj.DepPythonPipInstall(app))
+		handleError(proj.DepRubyGemInstall(app))
 	},
 }
 
@@ -93,6 +96,8 @@ func appCmdSelectApp(proj *core.Project) *def.App {
 func init() {
 	appCmd.PersistentFlags().StringP("service", "s", "", "name of application")
 	appShellCmd.PersistentFlags().StringP("execute", "e", "", "command to execute")
+	appBuildCmd.PersistentFlags().Bool("dev", false, "keep composer dev dependencies in the composer build flavor")
+	appInstallDepsCmd.PersistentFlags().BoolP("update", "u", false, "update dependencies and refresh lockfiles")
 	appCmd.AddCommand(appShellCmd)
 	appCmd.AddCommand(appBuildCmd)
 	appCmd.AddCommand(appDeployCmd)
@@ -8,19 +8,21 @@ import (
 	"gitlab.com/contextualcode/platform_cc/v2/pkg/output"
 )
 
+var databaseServiceTypes = []string{"mariadb", "mysql", "oracle-mysql", "mongodb", "mongodb-enterprise"}
+
 var databaseCmd = &cobra.Command{
 	Use:     "database [-s service] [-d database]",
-	Aliases: []string{"mysql", "mariadb", "db"},
+	Aliases: []string{"mysql", "mariadb", "mongodb", "db"},
 	Short:   "Manage database services.",
 }
 
 var databaseSql = &cobra.Command{
 	Use:   "sql",
-	Short: "Access SQL shell.",
+	Short: "Access SQL (or mongosh) shell.",
 	Run: func(cmd *cobra.Command, args []string) {
 		proj, err := getProject()
 		handleError(err)
-		serv, err := getService(databaseCmd, proj, []string{"mariadb", "mysql"})
+		serv, err := getService(databaseCmd, proj, databaseServiceTypes)
 		handleError(err)
 		brewServiceList, err := core.LoadServiceList()
 		handleError(err)
@@ -28,17 +30,22 @@ var databaseSql = &cobra.Command{
 		handleError(err)
 		database := databaseCmd.PersistentFlags().Lookup("database").Value.String()
 		database = proj.ResolveDatabase(database)
+		brewService.SetDefinition(proj, &serv)
+		if brewService.IsMongoDB() {
+			handleError(brewService.MongoDBShell(database))
+			return
+		}
 		handleError(brewService.MySQLShell(database))
 	},
 }
 
 var databaseDump = &cobra.Command{
 	Use:   "dump",
-	Short: "Dump SQL database.",
+	Short: "Dump SQL database (or mongodump archive).",
 	Run: func(cmd *cobra.Command, args []string) {
 		proj, err := getProject()
 		handleError(err)
-		serv, err := getService(databaseCmd, proj, []string{"mariadb", "mysql"})
+		serv, err := getService(databaseCmd, proj, databaseServiceTypes)
 		handleError(err)
 		brewServiceList, err := core.LoadServiceList()
 		handleError(err)
@@ -46,6 +53,11 @@ var databaseDump = &cobra.Command{
 		handleError(err)
 		database := databaseCmd.PersistentFlags().Lookup("database").Value.String()
 		database = proj.ResolveDatabase(database)
+		brewService.SetDefinition(proj, &serv)
+		if brewService.IsMongoDB() {
+			handleError(brewService.MongoDBDump(database))
+			return
+		}
 		handleError(brewService.MySQLDump(database))
 	},
 }
@@ -56,13 +68,17 @@ var databaseListSchemas = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		proj, err := getProject()
 		handleError(err)
-		serv, err := getService(databaseCmd, proj, []string{"mariadb", "mysql"})
+		serv, err := getService(databaseCmd, proj, databaseServiceTypes)
+		handleError(err)
+		brewServiceList, err := core.LoadServiceList()
+		handleError(err)
+		brewService, err := brewServiceList.MatchDef(serv)
 		handleError(err)
-		brewService := core.Service{
-			BrewName: "mysql",
-		}
 		brewService.SetDefinition(proj, &serv)
 		schemeas := brewService.MySQLGetSchemas()
+		if brewService.IsMongoDB() {
+			schemeas = brewService.MongoDBGetDatabases()
+		}
 		// json
 		if cmd.PersistentFlags().Lookup("json").Value.String() == "true" {
 			schemasJson, err := json.Marshal(schemeas)
@@ -0,0 +1,232 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"gitlab.com/contextualcode/pbrew/core"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/output"
+)
+
+var phpCmd = &cobra.Command{
+	Use:   "php [-s service]",
+	Short: "Manage PHP applications.",
+}
+
+var phpExtCmd = &cobra.Command{
+	Use:     "ext",
+	Aliases: []string{"extension", "extensions"},
+	Short:   "Manage PHP extensions.",
+}
+
+// phpCmdGetService returns the php brew service for the selected application of the project at the current working directory.
+func phpCmdGetService() *core.Service {
+	proj, err := getProject()
+	handleError(err)
+	app := proj.Apps[0]
+	appName := phpCmd.PersistentFlags().Lookup("service").Value.String()
+	if appName != "" {
+		app = nil
+		for _, sapp := range proj.Apps {
+			if sapp.Name == appName {
+				app = sapp
+				break
+			}
+		}
+		if app == nil {
+			handleError(errors.WithStack(errors.WithMessage(ErrServiceNotFound, appName)))
+		}
+	}
+	brewServiceList, err := core.LoadServiceList()
+	handleError(err)
+	brewService, err := brewServiceList.MatchDef(app)
+	handleError(err)
+	if !brewService.IsPHP() {
+		handleError(errors.WithStack(errors.WithMessage(core.ErrInvalidService, app.Name)))
+	}
+	brewService.SetDefinition(proj, app)
+	return brewService
+}
+
+var phpExtListCmd = &cobra.Command{
+	Use:   "list [--json]",
+	Short: "List PHP extensions for application.",
+	Run: func(cmd *cobra.Command, args []string) {
+		brewService := phpCmdGetService()
+		statuses, err := brewService.PHPExtensionStatuses()
+		handleError(err)
+		// json
+		if cmd.PersistentFlags().Lookup("json").Value.String() == "true" {
+			jsonOut, err := json.Marshal(statuses)
+			handleError(err)
+			output.WriteStdout(string(jsonOut) + "\n")
+			return
+		}
+		// table
+		tableRows := make([][]string, 0)
+		for _, status := range statuses {
+			version := status.Version
+			if status.Builtin {
+				version = "builtin"
+			} else if !status.HasRecipe {
+				version = "no recipe"
+			} else if version == "" {
+				version = "not supported"
+			}
+			installed := fmt.Sprintf("%t", status.Installed)
+			if status.Installed && status.InstalledVersion != "" {
+				installed = status.InstalledVersion
+			}
+			tableRows = append(tableRows, []string{
+				status.Name,
+				version,
+				installed,
+				fmt.Sprintf("%t", status.Requested),
+				fmt.Sprintf("%t", status.Enabled),
+			})
+		}
+		drawTable(
+			[]string{"EXTENSION", "VERSION", "INSTALLED", "REQUESTED", "ENABLED"},
+			tableRows,
+		)
+	},
+}
+
+var phpExtInstallCmd = &cobra.Command{
+	Use:   "install extension...",
+	Short: "Install PHP extensions for application's PHP version.",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		brewService := phpCmdGetService()
+		for _, name := range args {
+			handleError(brewService.PHPInstallExtension(name))
+		}
+		handleError(brewService.PHPApplyExtensions())
+	},
+}
+
+var phpExtRemoveCmd = &cobra.Command{
+	Use:   "remove extension...",
+	Short: "Remove PHP extensions from application's PHP version.",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		brewService := phpCmdGetService()
+		for _, name := range args {
+			handleError(brewService.PHPRemoveExtension(name))
+		}
+		handleError(brewService.PHPApplyExtensions())
+	},
+}
+
+var phpExtEnableCmd = &cobra.Command{
+	Use:   "enable extension...",
+	Short: "Enable PHP extensions for application.",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		brewService := phpCmdGetService()
+		for _, name := range args {
+			handleError(brewService.PHPEnableExtension(name))
+		}
+		handleError(brewService.PHPApplyExtensions())
+	},
+}
+
+var phpExtDisableCmd = &cobra.Command{
+	Use:   "disable extension...",
+	Short: "Disable PHP extensions for application.",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		brewService := phpCmdGetService()
+		for _, name := range args {
+			handleError(brewService.PHPDisableExtension(name))
+		}
+		handleError(brewService.PHPApplyExtensions())
+	},
+}
+
+var phpXdebugCmd = &cobra.Command{
+	Use:       "xdebug on|off|status [--mode debug,coverage]",
+	Short:     "Toggle xdebug for application.",
+	Args:      cobra.ExactValidArgs(1),
+	ValidArgs: []string{"on", "off", "status"},
+	Run: func(cmd *cobra.Command, args []string) {
+		brewService := phpCmdGetService()
+		switch args[0] {
+		case "on":
+			{
+				handleError(brewService.PHPXdebugOn(cmd.PersistentFlags().Lookup("mode").Value.String()))
+				handleError(brewService.PHPApplyExtensions())
+				break
+			}
+		case "off":
+			{
+				handleError(brewService.PHPXdebugOff())
+				handleError(brewService.PHPApplyExtensions())
+				break
+			}
+		}
+		status := brewService.PHPXdebugStatus()
+		if !status.Enabled {
+			output.WriteStdout("xdebug: off\n")
+			return
+		}
+		output.WriteStdout(fmt.Sprintf(
+			"xdebug: on\nversion: %s\nmode: %s\nclient: %s:%d\n",
+			status.Version, status.Mode, status.ClientHost, status.ClientPort,
+		))
+	},
+}
+
+var phpConfigCmd = &cobra.Command{
+	Use:   "config [--json]",
+	Short: "Display effective php.ini and PHP-FPM settings for application.",
+	Run: func(cmd *cobra.Command, args []string) {
+		brewService := phpCmdGetService()
+		config, err := brewService.PHPConfig()
+		handleError(err)
+		// json
+		if cmd.PersistentFlags().Lookup("json").Value.String() == "true" {
+			jsonOut, err := json.Marshal(config)
+			handleError(err)
+			output.WriteStdout(string(jsonOut) + "\n")
+			return
+		}
+		// tables
+		for _, section := range []struct {
+			name   string
+			values []core.PHPConfigValue
+		}{
+			{"php.ini", config.Ini},
+			{"php-fpm global", config.FpmGlobal},
+			{"php-fpm pool", config.FpmPool},
+		} {
+			if len(section.values) == 0 {
+				continue
+			}
+			tableRows := make([][]string, 0)
+			for _, value := range section.values {
+				tableRows = append(tableRows, []string{value.Key, value.Value, value.Source})
+			}
+			output.WriteStdout("\n >> " + section.name + "\n")
+			drawTable([]string{"KEY", "VALUE", "SOURCE"}, tableRows)
+		}
+	},
+}
+
+func init() {
+	phpCmd.PersistentFlags().StringP("service", "s", "", "name of application")
+	phpExtListCmd.PersistentFlags().Bool("json", false, "output in json")
+	phpExtCmd.AddCommand(phpExtListCmd)
+	phpExtCmd.AddCommand(phpExtInstallCmd)
+	phpExtCmd.AddCommand(phpExtRemoveCmd)
+	phpExtCmd.AddCommand(phpExtEnableCmd)
+	phpExtCmd.AddCommand(phpExtDisableCmd)
+	phpXdebugCmd.PersistentFlags().String("mode", "", "comma separated xdebug modes (default debug)")
+	phpCmd.AddCommand(phpExtCmd)
+	phpConfigCmd.PersistentFlags().Bool("json", false, "output in json")
+	phpCmd.AddCommand(phpXdebugCmd)
+	phpCmd.AddCommand(phpConfigCmd)
+	RootCmd.AddCommand(phpCmd)
+}
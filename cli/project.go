@@ -3,6 +3,7 @@ package cli
 import (
 	"encoding/json"
 	"fmt"
+	"path/filepath"
 	"strings"
 
 	"github.com/pkg/errors"
@@ -18,7 +19,7 @@ var projectCmd = &cobra.Command{
 }
 
 var projectStartCmd = &cobra.Command{
-	Use:   "start [--no-mounts] [-b use-pbrew-bottles]",
+	Use:   "start [--no-mounts] [-b use-pbrew-bottles] [--bypass-varnish]",
 	Short: "Start project.",
 	Run: func(cmd *cobra.Command, args []string) {
 		// start project
@@ -26,23 +27,22 @@ var projectStartCmd = &cobra.Command{
 		handleError(err)
 		proj.NoMounts = cmd.PersistentFlags().Lookup("no-mounts").Value.String() == "true"
 		proj.UsePbrewBottles = cmd.PersistentFlags().Lookup("use-pbrew-bottles").Value.String() == "true"
+		proj.BypassVarnish = cmd.PersistentFlags().Lookup("bypass-varnish").Value.String() == "true"
 		handleError(proj.Start())
-		// generate nginx
-		handleError(core.NginxAdd(proj))
-		// start/reload nginx
+		// add to router, reloads nginx if it's running
 		nginx := core.NginxService()
 		if nginx == nil {
 			handleError(errors.WithMessage(core.ErrServiceNotFound, "nginx"))
 		}
-		if nginx.IsRunning() {
-			handleError(nginx.Reload())
-			return
-		}
 		if !nginx.IsInstalled() {
 			handleError(nginx.Install())
 		}
-		handleError(nginx.PreStart())
-		handleError(nginx.Start())
+		handleError(core.NginxAdd(proj))
+		// start nginx
+		if !nginx.IsRunning() {
+			handleError(nginx.PreStart())
+			handleError(nginx.Start())
+		}
 	},
 }
 
@@ -54,9 +54,9 @@ var projectStopCmd = &cobra.Command{
 		proj, err := getProject()
 		handleError(err)
 		handleError(proj.Stop())
-		// generate nginx
+		// remove from router, reloads nginx if it's running
 		handleError(core.NginxDel(proj))
-		// reload nginx
+		// stop nginx when no projects are left
 		nginx := core.NginxService()
 		if nginx == nil {
 			handleError(errors.WithMessage(core.ErrServiceNotFound, "nginx"))
@@ -66,10 +66,7 @@ var projectStopCmd = &cobra.Command{
 			handleError(err)
 			if len(projectTracks) == 0 {
 				handleError(nginx.Stop())
-				return
 			}
-			handleError(nginx.Reload())
-			return
 		}
 	},
 }
@@ -129,6 +126,7 @@ var projectStatusCmd = &cobra.Command{
 		for _, brewService := range brewServices {
 			for _, serviceStatus := range serviceStatues {
 				if serviceStatus.Name == brewService.BrewAppName() || serviceStatus.Name == brewService.Name {
+					serviceStatus.RequestedVersion = brewService.RequestedVersion()
 					out = append(out, serviceStatus)
 					break
 				}
@@ -150,13 +148,86 @@ var projectStatusCmd = &cobra.Command{
 			rows = append(rows, []string{
 				v.DisplayName,
 				v.Name,
+				v.RequestedVersion,
+				v.Version,
 				v.Status,
 				strings.Join(ports, ","),
 			})
 		}
 		output.WriteStdout("\n >> " + proj.Name + "\n")
 		drawTable(
-			[]string{"NAME", "BREW NAME", "STATUS", "PORT"},
+			[]string{"NAME", "BREW NAME", "REQUESTED", "VERSION", "STATUS", "PORT"},
+			rows,
+		)
+	},
+}
+
+var projectSyncDomainCmd = &cobra.Command{
+	Use:   "sync-domain [--clear]",
+	Short: "Fetch project's default domain from Platform.sh.",
+	Run: func(cmd *cobra.Command, args []string) {
+		proj, err := getProject()
+		handleError(err)
+		if cmd.PersistentFlags().Lookup("clear").Value.String() == "true" {
+			handleError(core.ProjectDomainSet(proj, ""))
+		} else {
+			handleError(core.ProjectDomainSync(proj))
+			output.Info(fmt.Sprintf("Default domain is %s.", proj.DefaultDomain))
+		}
+		// update router host names
+		if core.NginxHas(proj) {
+			handleError(core.NginxAdd(proj))
+		}
+	},
+}
+
+var projectAppsCmd = &cobra.Command{
+	Use:   "apps [--json]",
+	Short: "List project's applications.",
+	Run: func(cmd *cobra.Command, args []string) {
+		proj, err := getProject()
+		handleError(err)
+		type appInfo struct {
+			Name         string   `json:"name"`
+			Path         string   `json:"path"`
+			Type         string   `json:"type"`
+			UpstreamPort int      `json:"upstream_port"`
+			Routes       []string `json:"routes"`
+		}
+		out := make([]appInfo, 0)
+		for _, app := range proj.Apps {
+			path, err := filepath.Rel(proj.Path, app.Path)
+			handleError(err)
+			out = append(out, appInfo{
+				Name:         app.Name,
+				Path:         path,
+				Type:         app.Type,
+				UpstreamPort: proj.GetUpstreamPort(app),
+				Routes:       proj.AppRoutes(app),
+			})
+		}
+		for _, route := range proj.UnmatchedRoutes() {
+			output.Warn(fmt.Sprintf("Route %s doesn't match an app or service.", route))
+		}
+		// json
+		if cmd.PersistentFlags().Lookup("json").Value.String() == "true" {
+			outJson, err := json.Marshal(out)
+			handleError(err)
+			output.WriteStdout(string(outJson) + "\n")
+			return
+		}
+		rows := make([][]string, 0)
+		for _, v := range out {
+			rows = append(rows, []string{
+				v.Name,
+				v.Path,
+				v.Type,
+				fmt.Sprintf("%d", v.UpstreamPort),
+				strings.Join(v.Routes, ","),
+			})
+		}
+		drawTable(
+			[]string{"NAME", "PATH", "TYPE", "UPSTREAM PORT", "ROUTES"},
 			rows,
 		)
 	},
@@ -165,10 +236,15 @@ var projectStatusCmd = &cobra.Command{
 func init() {
 	projectStartCmd.PersistentFlags().Bool("no-mounts", false, "disable symlink mounts")
 	projectStartCmd.PersistentFlags().BoolP("use-pbrew-bottles", "b", false, "enables use of pbrew provided bottles")
+	projectStartCmd.PersistentFlags().Bool("bypass-varnish", false, "route requests straight to the apps behind varnish")
 	projectStatusCmd.PersistentFlags().Bool("json", false, "output in json")
+	projectSyncDomainCmd.PersistentFlags().Bool("clear", false, "remove stored default domain")
+	projectAppsCmd.PersistentFlags().Bool("json", false, "output in json")
 	projectCmd.AddCommand(projectStartCmd)
 	projectCmd.AddCommand(projectStopCmd)
 	projectCmd.AddCommand(projectPurgeCmd)
 	projectCmd.AddCommand(projectStatusCmd)
+	projectCmd.AddCommand(projectSyncDomainCmd)
+	projectCmd.AddCommand(projectAppsCmd)
 	RootCmd.AddCommand(projectCmd)
 }
@@ -49,17 +49,21 @@ var routerStopCmd = &cobra.Command{
 }
 
 var routerAddCmd = &cobra.Command{
-	Use:   "add",
+	Use:   "add [--dry-run] [--bypass-varnish]",
 	Short: "Add project to router.",
 	Run: func(cmd *cobra.Command, args []string) {
 		proj, err := getProject()
 		handleError(err)
-		handleError(core.NginxAdd(proj))
-		nginx := core.NginxService()
-		if nginx == nil {
-			handleError(errors.WithMessage(core.ErrServiceNotFound, "nginx"))
+		proj.BypassVarnish = cmd.PersistentFlags().Lookup("bypass-varnish").Value.String() == "true"
+		if cmd.PersistentFlags().Lookup("dry-run").Value.String() == "true" {
+			configs, err := core.NginxConfigs(proj)
+			handleError(err)
+			for _, path := range core.NginxConfigPaths(configs) {
+				output.WriteStdout(fmt.Sprintf("# %s\n%s\n", path, configs[path]))
+			}
+			return
 		}
-		handleError(nginx.Reload())
+		handleError(core.NginxAdd(proj))
 	},
 }
 
@@ -71,11 +75,6 @@ var routerDelCmd = &cobra.Command{
 		proj, err := getProject()
 		handleError(err)
 		handleError(core.NginxDel(proj))
-		nginx := core.NginxService()
-		if nginx == nil {
-			handleError(errors.WithMessage(core.ErrServiceNotFound, "nginx"))
-		}
-		handleError(nginx.Reload())
 	},
 }
 
@@ -123,6 +122,7 @@ var routerListCmd = &cobra.Command{
 				strings.Join(upstreams, ","),
 			})
 		}
+		output.WriteStdout(fmt.Sprintf("\n >> https protocols: %s\n", core.GetNginxProtocols().String()))
 		drawTable(
 			[]string{"HOST", "NUMBER OF ROUTES", "UPSTREAMS"},
 			tableRows,
@@ -131,12 +131,169 @@ var routerListCmd = &cobra.Command{
 	},
 }
 
+var routerRequestsCmd = &cobra.Command{
+	Use:   "requests [-f] [--status 5xx] [--path prefix] [--summary] [--json]",
+	Short: "List requests from project access log.",
+	Run: func(cmd *cobra.Command, args []string) {
+		proj, err := getProject()
+		handleError(err)
+		filter := core.NginxRequestFilter{
+			Status:     cmd.PersistentFlags().Lookup("status").Value.String(),
+			PathPrefix: cmd.PersistentFlags().Lookup("path").Value.String(),
+		}
+		jsonOut := cmd.PersistentFlags().Lookup("json").Value.String() == "true"
+		requestRow := func(r core.NginxRequest) []string {
+			return []string{
+				r.Time,
+				fmt.Sprintf("%d", r.Status),
+				r.Method,
+				r.Host + r.URI,
+				fmt.Sprintf("%.0fms", r.RequestTime*1000),
+				r.App,
+			}
+		}
+		// follow
+		if cmd.PersistentFlags().Lookup("follow").Value.String() == "true" {
+			handleError(core.NginxFollowRequests(proj, filter, func(r core.NginxRequest) {
+				if jsonOut {
+					line, err := json.Marshal(r)
+					handleError(err)
+					output.WriteStdout(string(line) + "\n")
+					return
+				}
+				output.WriteStdout(strings.Join(requestRow(r), "\t") + "\n")
+			}))
+			return
+		}
+		requests, err := core.NginxRequests(proj, filter)
+		handleError(err)
+		// summary
+		if cmd.PersistentFlags().Lookup("summary").Value.String() == "true" {
+			stats := core.NginxRequestStats(requests)
+			if jsonOut {
+				out, err := json.Marshal(stats)
+				handleError(err)
+				output.WriteStdout(string(out) + "\n")
+				return
+			}
+			tableRows := make([][]string, 0)
+			for _, s := range stats {
+				tableRows = append(tableRows, []string{
+					s.Route,
+					s.App,
+					fmt.Sprintf("%d", s.Count),
+					fmt.Sprintf("%.0fms", s.P50*1000),
+					fmt.Sprintf("%.0fms", s.P95*1000),
+					fmt.Sprintf("%.1f%%", s.ErrorRate*100),
+				})
+			}
+			drawTable([]string{"ROUTE", "APP", "REQUESTS", "P50", "P95", "ERROR RATE"}, tableRows)
+			return
+		}
+		limit, err := cmd.PersistentFlags().GetInt("lines")
+		handleError(err)
+		if limit > 0 && len(requests) > limit {
+			requests = requests[len(requests)-limit:]
+		}
+		if jsonOut {
+			out, err := json.Marshal(requests)
+			handleError(err)
+			output.WriteStdout(string(out) + "\n")
+			return
+		}
+		tableRows := make([][]string, 0)
+		for _, r := range requests {
+			tableRows = append(tableRows, requestRow(r))
+		}
+		drawTable([]string{"TIME", "STATUS", "METHOD", "URL", "DURATION", "APP"}, tableRows)
+	},
+}
+
+var routerReplayCmd = &cobra.Command{
+	Use:   "replay <har-file|access-log> [--host host] [--all-methods] [--diff] [--json]",
+	Short: "Replay recorded requests against project routes.",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		proj, err := getProject()
+		handleError(err)
+		requests, err := core.LoadReplayRequests(args[0])
+		handleError(err)
+		defaultHost := cmd.PersistentFlags().Lookup("host").Value.String()
+		allMethods := cmd.PersistentFlags().Lookup("all-methods").Value.String() == "true"
+		diffOnly := cmd.PersistentFlags().Lookup("diff").Value.String() == "true"
+		results := make([]core.ReplayResult, 0)
+		count := 0
+		differences := 0
+		skippedHosts := proj.ReplayRequests(requests, defaultHost, allMethods, func(r core.ReplayResult) {
+			count++
+			isDifferent := r.Error != "" || (r.ExpectedStatus != 0 && r.Status != r.ExpectedStatus)
+			if isDifferent {
+				differences++
+			}
+			if !diffOnly || isDifferent {
+				results = append(results, r)
+			}
+		})
+		if skippedHosts > 0 {
+			output.Info(fmt.Sprintf("Skipped %d request(s) for hosts that aren't served by the project.", skippedHosts))
+		}
+		// json
+		if cmd.PersistentFlags().Lookup("json").Value.String() == "true" {
+			out, err := json.Marshal(results)
+			handleError(err)
+			output.WriteStdout(string(out) + "\n")
+			return
+		}
+		formatDuration := func(duration float64) string {
+			if duration == 0 {
+				return "-"
+			}
+			return fmt.Sprintf("%.0fms", duration*1000)
+		}
+		tableRows := make([][]string, 0)
+		for _, r := range results {
+			status := fmt.Sprintf("%d", r.Status)
+			if r.Error != "" {
+				status = r.Error
+			}
+			expectedStatus := "-"
+			if r.ExpectedStatus != 0 {
+				expectedStatus = fmt.Sprintf("%d", r.ExpectedStatus)
+			}
+			tableRows = append(tableRows, []string{
+				r.Method,
+				r.URL,
+				expectedStatus,
+				status,
+				formatDuration(r.ExpectedDuration),
+				formatDuration(r.Duration),
+			})
+		}
+		drawTable([]string{"METHOD", "URL", "RECORDED STATUS", "STATUS", "RECORDED TIME", "TIME"}, tableRows)
+		output.WriteStdout(fmt.Sprintf("%d request(s) replayed, %d with a different status.\n", count, differences))
+	},
+}
+
 func init() {
 	routerListCmd.PersistentFlags().Bool("json", false, "output as json")
+	routerAddCmd.PersistentFlags().Bool("dry-run", false, "print generated config without adding it")
+	routerAddCmd.PersistentFlags().Bool("bypass-varnish", false, "route requests straight to the apps behind varnish")
+	routerRequestsCmd.PersistentFlags().BoolP("follow", "f", false, "follow new requests")
+	routerRequestsCmd.PersistentFlags().String("status", "", "comma separated status codes to show, x matches any digit (e.g. 5xx)")
+	routerRequestsCmd.PersistentFlags().String("path", "", "only show requests with given path prefix")
+	routerRequestsCmd.PersistentFlags().Bool("summary", false, "show request count, latency and error rate per route")
+	routerRequestsCmd.PersistentFlags().IntP("lines", "n", 50, "number of most recent requests to show, 0 for all")
+	routerRequestsCmd.PersistentFlags().Bool("json", false, "output as json")
+	routerReplayCmd.PersistentFlags().String("host", "", "host to send requests without a host to, requests for this host are also replayed")
+	routerReplayCmd.PersistentFlags().Bool("all-methods", false, "also replay requests that could change state (POST, PUT, DELETE...)")
+	routerReplayCmd.PersistentFlags().Bool("diff", false, "only show requests with a different status")
+	routerReplayCmd.PersistentFlags().Bool("json", false, "output as json")
 	routerCmd.AddCommand(routerStartCmd)
 	routerCmd.AddCommand(routerStopCmd)
 	routerCmd.AddCommand(routerAddCmd)
 	routerCmd.AddCommand(routerDelCmd)
 	routerCmd.AddCommand(routerListCmd)
+	routerCmd.AddCommand(routerRequestsCmd)
+	routerCmd.AddCommand(routerReplayCmd)
 	RootCmd.AddCommand(routerCmd)
 }
@@ -0,0 +1,98 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"gitlab.com/contextualcode/pbrew/core"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/output"
+)
+
+var solrCmd = &cobra.Command{
+	Use:   "solr [-s service]",
+	Short: "Manage Solr services.",
+}
+
+// solrCmdGetService returns the solr brew service for the project at the current working directory.
+func solrCmdGetService() *core.Service {
+	proj, err := getProject()
+	handleError(err)
+	serv, err := getService(solrCmd, proj, []string{"solr"})
+	handleError(err)
+	brewServiceList, err := core.LoadServiceList()
+	handleError(err)
+	brewService, err := brewServiceList.MatchDef(serv)
+	handleError(err)
+	brewService.SetDefinition(proj, &serv)
+	if !brewService.IsRunning() {
+		handleError(errors.WithMessage(core.ErrServiceNotRunning, brewService.DisplayName()))
+	}
+	return brewService
+}
+
+var solrCoresCmd = &cobra.Command{
+	Use:   "cores [--json]",
+	Short: "List Solr cores for current project.",
+	Run: func(cmd *cobra.Command, args []string) {
+		brewService := solrCmdGetService()
+		statuses, err := brewService.SolrCoreStatuses()
+		handleError(err)
+		// json
+		if cmd.PersistentFlags().Lookup("json").Value.String() == "true" {
+			jsonOut, err := json.Marshal(statuses)
+			handleError(err)
+			output.WriteStdout(string(jsonOut) + "\n")
+			return
+		}
+		// table
+		tableRows := make([][]string, 0)
+		for _, status := range statuses {
+			config := "current"
+			if !status.Defined {
+				config = "not defined"
+			} else if status.ConfigChanged {
+				config = "changed"
+			}
+			tableRows = append(tableRows, []string{
+				status.Name,
+				fmt.Sprintf("%t", status.Loaded),
+				config,
+			})
+		}
+		drawTable(
+			[]string{"CORE", "LOADED", "CONFIG"},
+			tableRows,
+		)
+	},
+}
+
+var solrSyncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "Create, update and unload Solr cores to match services.yaml.",
+	Run: func(cmd *cobra.Command, args []string) {
+		brewService := solrCmdGetService()
+		handleError(brewService.SolrAddConfigSets())
+		handleError(brewService.SolrSyncCores(false))
+	},
+}
+
+var solrReindexConfigCmd = &cobra.Command{
+	Use:   "reindex-config",
+	Short: "Push configuration to every Solr core and reload them.",
+	Run: func(cmd *cobra.Command, args []string) {
+		brewService := solrCmdGetService()
+		handleError(brewService.SolrAddConfigSets())
+		handleError(brewService.SolrSyncCores(true))
+	},
+}
+
+func init() {
+	solrCmd.PersistentFlags().StringP("service", "s", "", "name of solr service")
+	solrCoresCmd.PersistentFlags().Bool("json", false, "output in json")
+	solrCmd.AddCommand(solrCoresCmd)
+	solrCmd.AddCommand(solrSyncCmd)
+	solrCmd.AddCommand(solrReindexConfigCmd)
+	RootCmd.AddCommand(solrCmd)
+}
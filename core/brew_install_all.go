@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"sort"
 
+	"github.com/pkg/errors"
+
 	"gitlab.com/contextualcode/platform_cc/v2/pkg/output"
 )
 
@@ -47,6 +49,9 @@ func BrewInstallAll(reinstall bool) error {
 		if def.IsPHP() {
 			for name := range phpExtList {
 				if err := def.PHPInstallExtension(name); err != nil {
+					if errors.Is(err, ErrPHPExtVersionNotSupported) {
+						continue
+					}
 					return err
 				}
 			}
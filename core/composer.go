@@ -0,0 +1,152 @@
+package core
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/def"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/output"
+)
+
+const composerDefaultMajorVersion = 2
+const composerDownloadURL = "https://getcomposer.org/download/latest-%d.x/composer.phar"
+
+// composerBuildFlavorFlags are the flags Platform.sh uses for the composer build flavor.
+var composerBuildFlavorFlags = []string{"--no-ansi", "--no-interaction", "install", "--no-progress", "--prefer-dist", "--optimize-autoloader", "--no-dev"}
+
+var composerMajorVersionRegex = regexp.MustCompile(`[0-9]+`)
+
+// composerMajorVersion returns the composer major version declared in dependencies.php.composer/composer.
+func composerMajorVersion(d *def.App) int {
+	constraint := d.Dependencies.PHP.Require["composer/composer"]
+	major, err := strconv.Atoi(composerMajorVersionRegex.FindString(constraint))
+	if err != nil || major < 1 {
+		return composerDefaultMajorVersion
+	}
+	return major
+}
+
+// composerBinDir returns the directory containing the composer binary for given major version.
+func composerBinDir(major int) string {
+	return filepath.Join(GetDir(BottleDir), "composer", fmt.Sprintf("%d", major))
+}
+
+// composerBinPath returns the path to the composer binary for given major version.
+func composerBinPath(major int) string {
+	return filepath.Join(composerBinDir(major), "composer")
+}
+
+// composerFetch downloads and verifies the latest composer release for given major version, unless it's cached.
+func composerFetch(major int) error {
+	binPath := composerBinPath(major)
+	if _, err := os.Stat(binPath); err == nil {
+		return nil
+	}
+	done := output.Duration(fmt.Sprintf("Download Composer %d.", major))
+	if err := os.MkdirAll(filepath.Dir(binPath), mkdirPerm); err != nil {
+		return errors.WithStack(err)
+	}
+	url := fmt.Sprintf(composerDownloadURL, major)
+	tmpPath := binPath + ".download"
+	checksumPath := tmpPath + ".sha256sum"
+	defer os.Remove(tmpPath)
+	defer os.Remove(checksumPath)
+	if err := downloadFile(url+".sha256sum", checksumPath); err != nil {
+		return err
+	}
+	checksumRaw, err := ioutil.ReadFile(checksumPath)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	checksumFields := strings.Fields(string(checksumRaw))
+	if len(checksumFields) == 0 {
+		return errors.WithStack(errors.WithMessage(ErrChecksumNotFound, url))
+	}
+	if err := downloadFile(url, tmpPath); err != nil {
+		return err
+	}
+	if err := verifySHA256(tmpPath, checksumFields[0]); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpPath, 0755); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := os.Rename(tmpPath, binPath); err != nil {
+		return errors.WithStack(err)
+	}
+	done()
+	return nil
+}
+
+// verifySHA256 checks that the file at given path has given SHA-256 checksum.
+func verifySHA256(path string, checksum string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return errors.WithStack(err)
+	}
+	if hex.EncodeToString(h.Sum(nil)) != strings.ToLower(checksum) {
+		return errors.WithStack(errors.WithMessage(ErrChecksumMismatch, filepath.Base(path)))
+	}
+	return nil
+}
+
+// composerCommand returns the command that runs the application's composer version with given arguments.
+func (p *Project) composerCommand(d *def.App, args ...string) (string, error) {
+	major := composerMajorVersion(d)
+	if err := composerFetch(major); err != nil {
+		return "", err
+	}
+	serviceList, err := LoadServiceList()
+	if err != nil {
+		return "", err
+	}
+	brewService, err := serviceList.MatchDef(d)
+	if err != nil {
+		return "", err
+	}
+	phpBinPath := filepath.Join(GetDir(BrewDir), "opt", brewService.BrewAppName(), "bin", "php")
+	return strings.Join(append([]string{phpBinPath, composerBinPath(major)}, args...), " "), nil
+}
+
+// hasComposerBuildFlavor returns true if the application explicitly uses the composer build flavor.
+func hasComposerBuildFlavor(d *def.App) bool {
+	return d.GetTypeName() == "php" && d.Build.Flavor == "composer"
+}
+
+// composerBuildFlavor runs composer install in the application root like the Platform.sh composer build flavor.
+func (p *Project) composerBuildFlavor(d *def.App) error {
+	if !hasComposerBuildFlavor(d) {
+		return nil
+	}
+	done := output.Duration(fmt.Sprintf("Run composer build flavor for %s.", d.Name))
+	args := make([]string, 0)
+	for _, flag := range composerBuildFlavorFlags {
+		if flag == "--no-dev" && p.ComposerDev {
+			continue
+		}
+		args = append(args, flag)
+	}
+	cmdStr, err := p.composerCommand(d, append(args, "-d", d.Path)...)
+	if err != nil {
+		return err
+	}
+	if err := p.Command(d, cmdStr); err != nil {
+		return err
+	}
+	done()
+	return nil
+}
@@ -16,8 +16,18 @@ type Config struct {
 	UserDir          string            `yaml:"user_dir"`
 	RouterHTTP       int               `yaml:"router_http_port"`
 	RouterHTTPS      int               `yaml:"router_https_port"`
+	RouterHTTP2      bool              `yaml:"router_http2"`
+	RouterHTTP3      bool              `yaml:"router_http3"`
 	Shell            string            `yaml:"shell"`
 	ServiceOverrides []ServiceOverride `yaml:"service_overrides"`
+	SolrMirror       string            `yaml:"solr_mirror"`
+	SolrArchiveDir   string            `yaml:"solr_archive_dir"`
+	SolrSkipChecksum bool              `yaml:"solr_skip_checksum"`
+	XdebugClientHost string            `yaml:"xdebug_client_host"`
+	XdebugClientPort int               `yaml:"xdebug_client_port"`
+	PHPMemoryBudget  int               `yaml:"php_memory_budget"`
+	ManageHosts      bool              `yaml:"manage_hosts"`
+	LocalDomain      string            `yaml:"local_domain"`
 }
 
 // DefaultConfig returns the default configuration settings.
@@ -27,8 +37,14 @@ func DefaultConfig() Config {
 		UserDir:          "~/.pbrew",
 		RouterHTTP:       80,
 		RouterHTTPS:      443,
+		RouterHTTP2:      true,
 		Shell:            "bash",
 		ServiceOverrides: make([]ServiceOverride, 0),
+		SolrMirror:       solrDefaultMirror,
+		XdebugClientHost: "localhost",
+		XdebugClientPort: 9003,
+		PHPMemoryBudget:  1024,
+		LocalDomain:      defaultLocalDomain,
 	}
 }
 
@@ -3,18 +3,30 @@ package core
 import "errors"
 
 var (
-	ErrBrewNotInstalled        = errors.New("homebrew not installed")
-	ErrServiceNotFound         = errors.New("service not found")
-	ErrServiceNotInstalled     = errors.New("service not installed")
-	ErrInvalidService          = errors.New("invalid service")
-	ErrInvalidDef              = errors.New("invalid definition")
-	ErrTemplateNotFound        = errors.New("service config template not found")
-	ErrServiceAlreadyRunning   = errors.New("service already running")
-	ErrServiceNotRunning       = errors.New("service not running")
-	ErrServiceReloadNotDefined = errors.New("service reload command not defined")
-	ErrServiceNotMySQL         = errors.New("service must be based on mysql")
-	ErrServiceNotSolr          = errors.New("service must be based on solr")
-	ErrServiceDefNotDefined    = errors.New("service definition not defined")
-	ErrPHPExtNotFound          = errors.New("php extension not found")
-	ErrProjectNotFound         = errors.New("project not found")
+	ErrBrewNotInstalled          = errors.New("homebrew not installed")
+	ErrServiceNotFound           = errors.New("service not found")
+	ErrServiceNotInstalled       = errors.New("service not installed")
+	ErrInvalidService            = errors.New("invalid service")
+	ErrInvalidDef                = errors.New("invalid definition")
+	ErrTemplateNotFound          = errors.New("service config template not found")
+	ErrServiceAlreadyRunning     = errors.New("service already running")
+	ErrServiceNotRunning         = errors.New("service not running")
+	ErrServiceReloadNotDefined   = errors.New("service reload command not defined")
+	ErrServiceNotMySQL           = errors.New("service must be based on mysql")
+	ErrServiceNotSolr            = errors.New("service must be based on solr")
+	ErrSolrCoreAdmin             = errors.New("solr core admin request failed")
+	ErrServiceNotMongoDB         = errors.New("service must be based on mongodb")
+	ErrServiceDefNotDefined      = errors.New("service definition not defined")
+	ErrPHPExtNotFound            = errors.New("php extension not found")
+	ErrPHPExtNotInstalled        = errors.New("php extension not installed")
+	ErrPHPExtVersionNotSupported = errors.New("php extension not supported by php version")
+	ErrInvalidXdebugMode         = errors.New("invalid xdebug mode")
+	ErrProjectNotFound           = errors.New("project not found")
+	ErrChecksumNotFound          = errors.New("checksum not found")
+	ErrChecksumMismatch          = errors.New("checksum mismatch")
+	ErrNodeVersionNotFound       = errors.New("node.js version not found")
+	ErrPythonVersionNotFound     = errors.New("python version not found")
+	ErrNginxConfigInvalid        = errors.New("nginx config test failed")
+	ErrDefaultDomainNotFound     = errors.New("default domain not found")
+	ErrReplayHostUnknown         = errors.New("host isn't served by the project")
 )
@@ -1,6 +1,7 @@
 package core
 
 import (
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -25,20 +26,28 @@ func scanPlatformAppYaml(topPath string, disableOverrides bool) [][]string {
 	o := make([][]string, 0)
 	appYamlPaths := make([]string, 0)
 	filepath.Walk(topPath, func(path string, f os.FileInfo, err error) error {
-		// check sub directory
-		if f.IsDir() && f.Name() != "." && path != topPath {
-			for _, appYamlFilename := range appYamlFilenames {
-				possiblePath := filepath.Join(path, appYamlFilename)
-				if _, err := os.Stat(possiblePath); !os.IsNotExist(err) {
-					appYamlPaths = append(appYamlPaths, possiblePath)
+		if err != nil || f == nil {
+			return nil
+		}
+		if !f.IsDir() {
+			return nil
+		}
+		// skip dependency and hidden directories, app directories can be nested
+		if path != topPath {
+			relPath, _ := filepath.Rel(topPath, path)
+			if strings.HasPrefix(f.Name(), ".") || len(strings.Split(relPath, string(os.PathSeparator))) > appScanMaxDepth {
+				return filepath.SkipDir
+			}
+			for _, skipDir := range appScanSkipDirs {
+				if f.Name() == skipDir {
+					return filepath.SkipDir
 				}
 			}
-			return filepath.SkipDir
 		}
-		// check root directory
 		for _, appYamlFilename := range appYamlFilenames {
-			if f.Name() == appYamlFilename {
-				appYamlPaths = append(appYamlPaths, path)
+			possiblePath := filepath.Join(path, appYamlFilename)
+			if _, err := os.Stat(possiblePath); !os.IsNotExist(err) {
+				appYamlPaths = append(appYamlPaths, possiblePath)
 			}
 		}
 		return nil
@@ -66,6 +75,35 @@ func scanPlatformAppYaml(topPath string, disableOverrides bool) [][]string {
 	return o
 }
 
+// hashDir writes the relative path and contents of every file in given directory to given hash.
+func hashDir(h io.Writer, dirPath string) error {
+	return filepath.Walk(dirPath, func(path string, f os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return errors.WithStack(err)
+		}
+		if f.IsDir() {
+			return nil
+		}
+		relPath, err := filepath.Rel(dirPath, path)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		io.WriteString(h, relPath)
+		file, err := os.Open(path)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		defer file.Close()
+		if _, err := io.Copy(h, file); err != nil {
+			return errors.WithStack(err)
+		}
+		return nil
+	})
+}
+
 func loadYAML(name string, out interface{}) error {
 	yamlRaw, err := ioutil.ReadFile(filepath.Join(GetDir(AppDir), "conf", name+".yaml"))
 	if err != nil {
@@ -76,3 +114,24 @@ func loadYAML(name string, out interface{}) error {
 	}
 	return nil
 }
+
+// sliceContains returns true if given slice contains given value.
+func sliceContains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// sliceRemove returns given slice without given value.
+func sliceRemove(values []string, value string) []string {
+	out := make([]string, 0)
+	for _, v := range values {
+		if v != value {
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,112 @@
+package core
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/output"
+)
+
+const hostsFilePath = "/etc/hosts"
+const hostsAddress = "127.0.0.1"
+
+// hostsBlockMarkers returns the comments that mark the start and end of given project's /etc/hosts block.
+func hostsBlockMarkers(p *Project) (string, string) {
+	return fmt.Sprintf("# pbrew %s start", p.Name), fmt.Sprintf("# pbrew %s end", p.Name)
+}
+
+// HostsHostNames returns the route host names of given project that can be resolved through /etc/hosts.
+// Wildcard hosts can't be listed in /etc/hosts and are skipped.
+func HostsHostNames(p *Project) []string {
+	out := make([]string, 0)
+	for _, hostName := range GetHostNames(p.Routes) {
+		hostName = ProjectDefaultHostName(p, hostName)
+		if strings.Contains(hostName, "*") || hostName == defaultHostName {
+			continue
+		}
+		out = append(out, hostName)
+	}
+	return out
+}
+
+// hostsUpdate replaces given project's block in /etc/hosts with given host names, an empty list removes the block.
+func hostsUpdate(p *Project, hostNames []string) error {
+	raw, err := ioutil.ReadFile(hostsFilePath)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	start, end := hostsBlockMarkers(p)
+	lines := make([]string, 0)
+	inBlock := false
+	for _, line := range strings.Split(strings.TrimRight(string(raw), "\n"), "\n") {
+		switch {
+		case strings.TrimSpace(line) == start:
+			inBlock = true
+		case strings.TrimSpace(line) == end:
+			inBlock = false
+		case !inBlock:
+			lines = append(lines, line)
+		}
+	}
+	if len(hostNames) > 0 {
+		lines = append(lines, start)
+		for _, hostName := range hostNames {
+			lines = append(lines, fmt.Sprintf("%s %s", hostsAddress, hostName))
+		}
+		lines = append(lines, end)
+	}
+	contents := strings.Join(lines, "\n") + "\n"
+	if contents == string(raw) {
+		return nil
+	}
+	done := output.Duration(fmt.Sprintf("Update %s.", hostsFilePath))
+	tmpPath := filepath.Join(GetDir(TempDir), "hosts")
+	if err := ioutil.WriteFile(tmpPath, []byte(contents), 0644); err != nil {
+		return errors.WithStack(err)
+	}
+	defer os.Remove(tmpPath)
+	// cp keeps the owner and permissions of the existing file
+	cmdStr := fmt.Sprintf("sudo cp %s %s", tmpPath, hostsFilePath)
+	if runtime.GOOS == "darwin" {
+		cmdStr += " && (dscacheutil -flushcache; sudo killall -HUP mDNSResponder) || true"
+	}
+	cmd := exec.Command("bash", "-c", cmdStr)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return errors.WithStack(err)
+	}
+	done()
+	return nil
+}
+
+// HostsAdd adds the route host names of given project to /etc/hosts when manage_hosts is enabled.
+func HostsAdd(p *Project) error {
+	config, err := LoadConfig()
+	if err != nil {
+		return err
+	}
+	if !config.ManageHosts {
+		return nil
+	}
+	return hostsUpdate(p, HostsHostNames(p))
+}
+
+// HostsDel removes the route host names of given project from /etc/hosts when manage_hosts is enabled.
+func HostsDel(p *Project) error {
+	config, err := LoadConfig()
+	if err != nil {
+		return err
+	}
+	if !config.ManageHosts {
+		return nil
+	}
+	return hostsUpdate(p, []string{})
+}
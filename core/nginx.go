@@ -2,14 +2,12 @@ package core
 
 import (
 	"fmt"
-	"io/ioutil"
 	"net/url"
 	"os"
 	"path/filepath"
 	"sort"
 	"strings"
 
-	"github.com/pkg/errors"
 	"gitlab.com/contextualcode/platform_cc/v2/pkg/def"
 	"gitlab.com/contextualcode/platform_cc/v2/pkg/output"
 )
@@ -22,18 +20,11 @@ const nginxStartCmd = `
 	sudo {BREW_PATH}/opt/nginx/bin/nginx -c {CONF_FILE} -p {BREW_PATH}/opt/nginx/ -e {LOG_PATH}/nginx_error.log
 `
 
-const nginxPostInstallCmd = `
-	openssl req -x509 -out {DATA_PATH}/localhost.crt -keyout {DATA_PATH}/localhost.key \
-		-newkey rsa:2048 -nodes -sha256 \
-		-subj '/CN=localhost' -extensions EXT -config <( \
-		printf "[dn]\nCN=localhost\n[req]\ndistinguished_name = dn\n[EXT]\nsubjectAltName=DNS:localhost\nkeyUsage=digitalSignature\nextendedKeyUsage=serverAuth")
-`
-
 // NginxService returns the service for nginx.
 func NginxService() *Service {
 	return &Service{
 		BrewName:        "nginx",
-		PostInstallCmd:  nginxPostInstallCmd,
+		PostInstallCmd:  strings.ReplaceAll(nginxCertCmd, "{SAN}", "DNS:localhost"),
 		StartCmd:        nginxStartCmd,
 		StopCmd:         "sudo {BREW_PATH}/opt/nginx/bin/nginx -c {CONF_FILE} -p {BREW_PATH}/opt/nginx/ -e {LOG_PATH}/nginx_error.log -s stop",
 		ReloadCmd:       "sudo {BREW_PATH}/opt/nginx/bin/nginx -c {CONF_FILE} -p {BREW_PATH}/opt/nginx/ -e {LOG_PATH}/nginx_error.log -s reload",
@@ -51,47 +42,40 @@ func NginxAppConfigPath(p *Project, def *def.App) string {
 	return filepath.Join(GetDir(ConfDir), fmt.Sprintf("nginx_app_%s_%s.conf", p.Name, def.Name))
 }
 
-// NginxAdd generates nginx config for given project.
+// NginxAdd generates nginx config for given project, tests it and reloads the router.
+// Route host names are added to /etc/hosts when manage_hosts is enabled.
 func NginxAdd(proj *Project) error {
 	if proj == nil {
 		return nil
 	}
 	done := output.Duration(fmt.Sprintf("Add '%s' to router.", proj.Name))
-	nginxRoutes, err := proj.GenerateNginxRoutes()
+	if err := nginxCertEnsure(proj.LocalDomain); err != nil {
+		return err
+	}
+	configs, err := NginxConfigs(proj)
 	if err != nil {
 		return err
 	}
-	if err := ioutil.WriteFile(
-		NginxRouteConfigPath(proj),
-		[]byte(nginxRoutes),
-		0655,
-	); err != nil {
-		return errors.WithStack(err)
+	if err := nginxApply(configs); err != nil {
+		return err
 	}
-	for _, app := range proj.Apps {
-		nginxApp, err := proj.GenerateNginxApp(app)
-		if err != nil {
-			return err
-		}
-		if err := ioutil.WriteFile(
-			NginxAppConfigPath(proj, app),
-			[]byte(nginxApp),
-			0655,
-		); err != nil {
-			return errors.WithStack(err)
-		}
+	if err := HostsAdd(proj); err != nil {
+		return err
 	}
 	done()
 	return nil
 }
 
-// NginxDel deletes nginx config for given project.
+// NginxDel deletes nginx config for given project and reloads the router.
 func NginxDel(proj *Project) error {
-	os.Remove(NginxRouteConfigPath(proj))
+	configs := map[string]string{NginxRouteConfigPath(proj): ""}
 	for _, app := range proj.Apps {
-		os.Remove(NginxAppConfigPath(proj, app))
+		configs[NginxAppConfigPath(proj, app)] = ""
 	}
-	return nil
+	if err := nginxApply(configs); err != nil {
+		return err
+	}
+	return HostsDel(proj)
 }
 
 // NginxHas returns true if project is currently loaded in to nginx router.
@@ -156,11 +140,12 @@ func GetRoutesForHostName(host string, routes []def.Route) []def.Route {
 }
 
 // ProjectDefaultHostName returns first for hostname with {default} tag.
+// {default} is the project's default domain, internal host names already end with its local domain.
 func ProjectDefaultHostName(p *Project, host string) string {
+	host = strings.ReplaceAll(host, "{default}", "__PID__.default")
 	if p.DefaultDomain != "" { 
 		host = strings.ReplaceAll(host, "__PID__.default", p.DefaultDomain)
 	}
 	host = strings.ReplaceAll(host, "__PID__", p.Name)
-	host = strings.ReplaceAll(host, "{default}", p.Name)
 	return host
 }
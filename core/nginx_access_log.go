@@ -0,0 +1,244 @@
+package core
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const nginxAccessLogFollowInterval = 500 * time.Millisecond
+
+// NginxRequest is a request from a project's router access log.
+type NginxRequest struct {
+	Time         string  `json:"time"`
+	Host         string  `json:"host"`
+	Method       string  `json:"method"`
+	URI          string  `json:"uri"`
+	Status       int     `json:"status"`
+	Bytes        int     `json:"bytes"`
+	RequestTime  float64 `json:"request_time"`
+	UpstreamTime string  `json:"upstream_time"`
+	Upstream     string  `json:"upstream"`
+	App          string  `json:"app"`
+	Route        string  `json:"route"`
+	Referer      string  `json:"referer"`
+	UserAgent    string  `json:"user_agent"`
+}
+
+// NginxRequestFilter filters router requests.
+type NginxRequestFilter struct {
+	Status     string
+	PathPrefix string
+}
+
+// NginxRouteStats are request statistics for a route.
+type NginxRouteStats struct {
+	Route     string  `json:"route"`
+	App       string  `json:"app"`
+	Count     int     `json:"count"`
+	Errors    int     `json:"errors"`
+	ErrorRate float64 `json:"error_rate"`
+	P50       float64 `json:"p50"`
+	P95       float64 `json:"p95"`
+}
+
+// NginxAccessLogPath returns the path to the router access log of given project.
+func NginxAccessLogPath(p *Project) string {
+	return filepath.Join(GetDir(LogDir), fmt.Sprintf("nginx_access_%s.log", p.Name))
+}
+
+// ParseNginxRequest parses a line of the router access log.
+func ParseNginxRequest(line string) (NginxRequest, error) {
+	out := NginxRequest{}
+	if err := json.Unmarshal([]byte(line), &out); err != nil {
+		return out, errors.WithStack(err)
+	}
+	return out, nil
+}
+
+// Match returns true if given request matches the filter.
+// Status is a comma separated list of status codes where x matches any digit (e.g. 5xx,404).
+func (f NginxRequestFilter) Match(r NginxRequest) bool {
+	if f.PathPrefix != "" && !strings.HasPrefix(r.URI, f.PathPrefix) {
+		return false
+	}
+	if f.Status == "" {
+		return true
+	}
+	status := strconv.Itoa(r.Status)
+	for _, pattern := range strings.Split(f.Status, ",") {
+		pattern = strings.ToLower(strings.TrimSpace(pattern))
+		if len(pattern) != len(status) {
+			continue
+		}
+		matches := true
+		for i := range pattern {
+			if pattern[i] != 'x' && pattern[i] != status[i] {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			return true
+		}
+	}
+	return false
+}
+
+// nginxReadRequests reads requests matching given filter from given reader, lines that can't be parsed are skipped.
+func nginxReadRequests(r *bufio.Reader, filter NginxRequestFilter, callback func(NginxRequest)) error {
+	for {
+		line, err := r.ReadString('\n')
+		if strings.HasSuffix(line, "\n") {
+			if request, err := ParseNginxRequest(strings.TrimSpace(line)); err == nil && filter.Match(request) {
+				callback(request)
+			}
+		}
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return errors.WithStack(err)
+		}
+	}
+}
+
+// NginxRequests returns the requests in the router access log of given project that match given filter.
+func NginxRequests(p *Project, filter NginxRequestFilter) ([]NginxRequest, error) {
+	out := make([]NginxRequest, 0)
+	f, err := os.Open(NginxAccessLogPath(p))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return out, nil
+		}
+		return out, errors.WithStack(err)
+	}
+	defer f.Close()
+	err = nginxReadRequests(bufio.NewReader(f), filter, func(r NginxRequest) {
+		out = append(out, r)
+	})
+	return out, err
+}
+
+// NginxFollowRequests calls given callback for every new request in the router access log of given project that
+// matches given filter. It never returns unless an error occurs.
+func NginxFollowRequests(p *Project, filter NginxRequestFilter, callback func(NginxRequest)) error {
+	path := NginxAccessLogPath(p)
+	f, err := os.OpenFile(path, os.O_RDONLY|os.O_CREATE, 0644)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer func() {
+		f.Close()
+	}()
+	offset, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadString('\n')
+		offset += int64(len(line))
+		if err == io.EOF {
+			// wait for the rest of a partially written line
+			if line != "" {
+				offset -= int64(len(line))
+				if _, err := f.Seek(offset, io.SeekStart); err != nil {
+					return errors.WithStack(err)
+				}
+				r.Reset(f)
+			}
+			pathInfo, err := os.Stat(path)
+			if err != nil {
+				// the log was moved away and nginx hasn't created the new one yet
+				time.Sleep(nginxAccessLogFollowInterval)
+				continue
+			}
+			fileInfo, err := f.Stat()
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			if !os.SameFile(pathInfo, fileInfo) {
+				// the log was rotated, read the new one from the start
+				newFile, err := os.Open(path)
+				if err != nil {
+					return errors.WithStack(err)
+				}
+				f.Close()
+				f = newFile
+				offset = 0
+				r.Reset(f)
+				continue
+			}
+			if pathInfo.Size() < offset {
+				// the log was truncated, start over
+				if offset, err = f.Seek(0, io.SeekStart); err != nil {
+					return errors.WithStack(err)
+				}
+				r.Reset(f)
+				continue
+			}
+			time.Sleep(nginxAccessLogFollowInterval)
+			continue
+		} else if err != nil {
+			return errors.WithStack(err)
+		}
+		if request, err := ParseNginxRequest(strings.TrimSpace(line)); err == nil && filter.Match(request) {
+			callback(request)
+		}
+	}
+}
+
+// percentile returns the given percentile of sorted values using the nearest rank method.
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p/100*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	return sorted[rank]
+}
+
+// NginxRequestStats returns request counts, latency percentiles and error rates of given requests per route.
+func NginxRequestStats(requests []NginxRequest) []NginxRouteStats {
+	times := make(map[string][]float64)
+	stats := make(map[string]*NginxRouteStats)
+	for _, request := range requests {
+		route := request.Route
+		if route == "" {
+			route = request.Host
+		}
+		if stats[route] == nil {
+			stats[route] = &NginxRouteStats{Route: route, App: request.App}
+		}
+		stats[route].Count++
+		if request.Status >= 500 {
+			stats[route].Errors++
+		}
+		times[route] = append(times[route], request.RequestTime)
+	}
+	out := make([]NginxRouteStats, 0)
+	for route, s := range stats {
+		sort.Float64s(times[route])
+		s.P50 = percentile(times[route], 50)
+		s.P95 = percentile(times[route], 95)
+		s.ErrorRate = float64(s.Errors) / float64(s.Count)
+		out = append(out, *s)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return strings.Compare(out[i].Route, out[j].Route) < 0
+	})
+	return out
+}
@@ -0,0 +1,72 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+)
+
+// appendNginxAccessLog appends a request for each given uri to the access log at given path.
+func appendNginxAccessLog(t *testing.T, path string, flag int, uris ...string) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|flag, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	for _, uri := range uris {
+		if _, err := fmt.Fprintf(f, "{\"host\":\"localhost\",\"uri\":\"%s\",\"status\":200}\n", uri); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// expectNginxFollowRequests checks that exactly the given uris are received, in order.
+func expectNginxFollowRequests(t *testing.T, requests chan string, uris ...string) {
+	timeout := time.After(4 * nginxAccessLogFollowInterval)
+	for _, uri := range uris {
+		select {
+		case out := <-requests:
+			if out != uri {
+				t.Fatalf("expected request %s, got %s", uri, out)
+			}
+		case <-timeout:
+			t.Fatalf("expected request %s, got nothing", uri)
+		}
+	}
+	select {
+	case out := <-requests:
+		t.Fatalf("expected no more requests, got %s", out)
+	case <-time.After(2 * nginxAccessLogFollowInterval):
+	}
+}
+
+func TestNginxFollowRequestsRotation(t *testing.T) {
+	setupNginxRoutesTest(t, "")
+	p := &Project{Name: "follow"}
+	path := NginxAccessLogPath(p)
+	appendNginxAccessLog(t, path, os.O_APPEND, "/old")
+	requests := make(chan string, 100)
+	go NginxFollowRequests(p, NginxRequestFilter{}, func(r NginxRequest) {
+		requests <- r.URI
+	})
+	time.Sleep(nginxAccessLogFollowInterval)
+
+	appendNginxAccessLog(t, path, os.O_APPEND, "/before-rotate")
+	expectNginxFollowRequests(t, requests, "/before-rotate")
+
+	// rename style rotation
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatal(err)
+	}
+	appendNginxAccessLog(t, path, os.O_APPEND, "/after-rotate")
+	expectNginxFollowRequests(t, requests, "/after-rotate")
+
+	// copytruncate style rotation
+	if err := os.Truncate(path, 0); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(2 * nginxAccessLogFollowInterval)
+	appendNginxAccessLog(t, path, os.O_APPEND, "/after-truncate")
+	expectNginxFollowRequests(t, requests, "/after-truncate")
+}
@@ -0,0 +1,74 @@
+package core
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/output"
+)
+
+const nginxCertCmd = `
+	openssl req -x509 -out {DATA_PATH}/localhost.crt -keyout {DATA_PATH}/localhost.key \
+		-newkey rsa:2048 -nodes -sha256 \
+		-subj '/CN=localhost' -extensions EXT -config <( \
+		printf "[dn]\nCN=localhost\n[req]\ndistinguished_name = dn\n[EXT]\nsubjectAltName={SAN}\nkeyUsage=digitalSignature\nextendedKeyUsage=serverAuth")
+`
+
+// nginxCertDomainsPath returns the path to the file listing the local domains in the router certificate.
+func nginxCertDomainsPath() string {
+	return filepath.Join(NginxService().DataPath(), "localhost.domains")
+}
+
+// nginxCertDomains returns the local domains the router certificate was generated for.
+func nginxCertDomains() []string {
+	out := make([]string, 0)
+	raw, err := ioutil.ReadFile(nginxCertDomainsPath())
+	if err != nil {
+		return out
+	}
+	for _, domain := range strings.Split(string(raw), "\n") {
+		if strings.TrimSpace(domain) != "" {
+			out = append(out, strings.TrimSpace(domain))
+		}
+	}
+	return out
+}
+
+// nginxCertEnsure regenerates the router certificate when it doesn't cover given local domain yet.
+// Every local domain a project was added with stays in the certificate's subject alternative names.
+func nginxCertEnsure(domain string) error {
+	domains := nginxCertDomains()
+	for _, d := range domains {
+		if d == domain {
+			if _, err := os.Stat(filepath.Join(NginxService().DataPath(), "localhost.crt")); err == nil {
+				return nil
+			}
+		}
+	}
+	if domain != "" {
+		domains = append(domains, domain)
+	}
+	done := output.Duration("Generate router certificate.")
+	if err := os.MkdirAll(NginxService().DataPath(), mkdirPerm); err != nil {
+		return errors.WithStack(err)
+	}
+	san := []string{"DNS:localhost"}
+	for _, d := range domains {
+		san = append(san, fmt.Sprintf("DNS:%s", d), fmt.Sprintf("DNS:*.%s", d))
+	}
+	cmdStr := strings.ReplaceAll(NginxService().injectCommandParams(nginxCertCmd), "{SAN}", strings.Join(san, ","))
+	cmd := exec.Command("bash", "-c", cmdStr)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return errors.WithStack(errors.WithMessage(err, strings.TrimSpace(string(out))))
+	}
+	if err := ioutil.WriteFile(nginxCertDomainsPath(), []byte(strings.Join(domains, "\n")+"\n"), 0655); err != nil {
+		return errors.WithStack(err)
+	}
+	done()
+	return nil
+}
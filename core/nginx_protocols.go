@@ -0,0 +1,92 @@
+package core
+
+import (
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// nginxHTTP2DirectiveVersion is the nginx version that replaced the listen parameter with the http2 directive.
+var nginxHTTP2DirectiveVersion = []int{1, 25, 1}
+
+var nginxVersionRegex = regexp.MustCompile(`nginx/([0-9]+)\.([0-9]+)\.([0-9]+)`)
+
+var nginxBuildInfoCache *string
+
+// NginxProtocols are the protocols the router serves https routes with.
+type NginxProtocols struct {
+	Version        string `json:"version"`
+	HTTP2          bool   `json:"http2"`
+	HTTP3          bool   `json:"http3"`
+	HTTP2Directive bool   `json:"-"`
+}
+
+// nginxBuildInfo returns the output of nginx -V, which lists the version and the modules nginx was built with.
+func nginxBuildInfo() string {
+	if nginxBuildInfoCache != nil {
+		return *nginxBuildInfoCache
+	}
+	cmd := exec.Command("bash", "-c", NginxService().injectCommandParams("{BREW_PATH}/opt/nginx/bin/nginx -V"))
+	cmd.Env = brewEnv()
+	out, err := cmd.CombinedOutput()
+	info := ""
+	if err == nil {
+		info = string(out)
+	}
+	nginxBuildInfoCache = &info
+	return info
+}
+
+// nginxVersionAtLeast returns true if given version parts are at least the minimum version.
+func nginxVersionAtLeast(version []int, minimum []int) bool {
+	for i := range minimum {
+		if i >= len(version) || version[i] != minimum[i] {
+			return i < len(version) && version[i] > minimum[i]
+		}
+	}
+	return true
+}
+
+// GetNginxProtocols returns the protocols enabled in the config that the installed nginx supports.
+func GetNginxProtocols() NginxProtocols {
+	out := NginxProtocols{}
+	config, err := LoadConfig()
+	if err != nil {
+		return out
+	}
+	info := nginxBuildInfo()
+	version := make([]int, 0)
+	if match := nginxVersionRegex.FindStringSubmatch(info); match != nil {
+		for _, part := range match[1:] {
+			v, _ := strconv.Atoi(part)
+			version = append(version, v)
+		}
+		out.Version = strings.Join(match[1:], ".")
+	}
+	out.HTTP2 = config.RouterHTTP2 && strings.Contains(info, "--with-http_v2_module")
+	out.HTTP2Directive = out.HTTP2 && nginxVersionAtLeast(version, nginxHTTP2DirectiveVersion)
+	out.HTTP3 = config.RouterHTTP3 && strings.Contains(info, "--with-http_v3_module")
+	return out
+}
+
+// Names returns the names of the protocols https routes are served with.
+func (n NginxProtocols) Names() []string {
+	out := []string{"HTTP/1.1"}
+	if n.HTTP2 {
+		out = append(out, "HTTP/2")
+	}
+	if n.HTTP3 {
+		out = append(out, "HTTP/3")
+	}
+	return out
+}
+
+// String returns the protocols as a readable list.
+func (n NginxProtocols) String() string {
+	if n.Version == "" {
+		return strings.Join(n.Names(), ", ")
+	}
+	return fmt.Sprintf("%s (nginx %s)", strings.Join(n.Names(), ", "), n.Version)
+}
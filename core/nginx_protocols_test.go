@@ -0,0 +1,103 @@
+package core
+
+import (
+	"testing"
+)
+
+const nginxTestBuildInfo = "nginx version: nginx/1.25.3\nconfigure arguments: --with-http_v2_module --with-http_v3_module"
+
+func TestNginxVersionAtLeast(t *testing.T) {
+	tests := []struct {
+		version  []int
+		expected bool
+	}{
+		{version: []int{1, 25, 1}, expected: true},
+		{version: []int{1, 25, 3}, expected: true},
+		{version: []int{1, 26, 0}, expected: true},
+		{version: []int{2, 0, 0}, expected: true},
+		{version: []int{1, 25, 0}, expected: false},
+		{version: []int{1, 24, 9}, expected: false},
+		{version: []int{0, 99, 99}, expected: false},
+		{version: []int{1, 25}, expected: false},
+		{version: []int{}, expected: false},
+	}
+	for _, tt := range tests {
+		if out := nginxVersionAtLeast(tt.version, nginxHTTP2DirectiveVersion); out != tt.expected {
+			t.Errorf("expected %v for %v, got %v", tt.expected, tt.version, out)
+		}
+	}
+}
+
+func TestGetNginxProtocols(t *testing.T) {
+	tests := []struct {
+		name      string
+		buildInfo string
+		http2     bool
+		http3     bool
+		expected  NginxProtocols
+	}{
+		{
+			name:     "not installed",
+			http2:    true,
+			http3:    true,
+			expected: NginxProtocols{},
+		},
+		{
+			name:      "no modules",
+			buildInfo: "nginx version: nginx/1.25.3\nconfigure arguments: --with-http_ssl_module",
+			http2:     true,
+			http3:     true,
+			expected:  NginxProtocols{Version: "1.25.3"},
+		},
+		{
+			name:      "listen parameter",
+			buildInfo: "nginx version: nginx/1.24.0\nconfigure arguments: --with-http_v2_module",
+			http2:     true,
+			expected:  NginxProtocols{Version: "1.24.0", HTTP2: true},
+		},
+		{
+			name:      "http2 directive",
+			buildInfo: nginxTestBuildInfo,
+			http2:     true,
+			expected:  NginxProtocols{Version: "1.25.3", HTTP2: true, HTTP2Directive: true},
+		},
+		{
+			name:      "http3",
+			buildInfo: nginxTestBuildInfo,
+			http2:     true,
+			http3:     true,
+			expected:  NginxProtocols{Version: "1.25.3", HTTP2: true, HTTP2Directive: true, HTTP3: true},
+		},
+		{
+			name:      "disabled",
+			buildInfo: nginxTestBuildInfo,
+			expected:  NginxProtocols{Version: "1.25.3"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupNginxRoutesTest(t, tt.buildInfo)
+			loadedConfig.RouterHTTP2 = tt.http2
+			loadedConfig.RouterHTTP3 = tt.http3
+			if out := GetNginxProtocols(); out != tt.expected {
+				t.Errorf("expected %+v, got %+v", tt.expected, out)
+			}
+		})
+	}
+}
+
+func TestNginxProtocolsString(t *testing.T) {
+	tests := []struct {
+		protocols NginxProtocols
+		expected  string
+	}{
+		{protocols: NginxProtocols{}, expected: "HTTP/1.1"},
+		{protocols: NginxProtocols{Version: "1.25.3", HTTP2: true}, expected: "HTTP/1.1, HTTP/2 (nginx 1.25.3)"},
+		{protocols: NginxProtocols{Version: "1.25.3", HTTP2: true, HTTP3: true}, expected: "HTTP/1.1, HTTP/2, HTTP/3 (nginx 1.25.3)"},
+	}
+	for _, tt := range tests {
+		if out := tt.protocols.String(); out != tt.expected {
+			t.Errorf("expected %s, got %s", tt.expected, out)
+		}
+	}
+}
@@ -0,0 +1,332 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const replayTimeout = 60 * time.Second
+
+// replayCombinedLogRegex matches a line in nginx's combined log format.
+var replayCombinedLogRegex = regexp.MustCompile(`^\S+ \S+ \S+ \[[^\]]+\] "(\S+) (\S+)[^"]*" (\d{3}) \S+`)
+
+// replaySkipHeaders are recorded request headers that aren't replayed.
+var replaySkipHeaders = []string{"host", "content-length", "connection", "accept-encoding", "keep-alive", "transfer-encoding"}
+
+// ReplayRequest is a recorded request to replay against the router.
+type ReplayRequest struct {
+	Method   string            `json:"method"`
+	Scheme   string            `json:"scheme"`
+	Host     string            `json:"host"`
+	URI      string            `json:"uri"`
+	Headers  map[string]string `json:"headers"`
+	Body     string            `json:"body"`
+	Status   int               `json:"status"`
+	Duration float64           `json:"duration"`
+}
+
+// ReplayResult is the outcome of replaying a request.
+type ReplayResult struct {
+	Method           string  `json:"method"`
+	URL              string  `json:"url"`
+	ExpectedStatus   int     `json:"expected_status"`
+	Status           int     `json:"status"`
+	ExpectedDuration float64 `json:"expected_duration"`
+	Duration         float64 `json:"duration"`
+	Error            string  `json:"error,omitempty"`
+}
+
+type harFile struct {
+	Log struct {
+		Entries []struct {
+			Time    float64 `json:"time"`
+			Request struct {
+				Method  string `json:"method"`
+				URL     string `json:"url"`
+				Headers []struct {
+					Name  string `json:"name"`
+					Value string `json:"value"`
+				} `json:"headers"`
+				PostData struct {
+					Text string `json:"text"`
+				} `json:"postData"`
+			} `json:"request"`
+			Response struct {
+				Status int `json:"status"`
+			} `json:"response"`
+		} `json:"entries"`
+	} `json:"log"`
+}
+
+// LoadReplayRequests reads recorded requests from a HAR file or an access log. Access logs can be in pbrew's
+// router log format or nginx's combined log format, which doesn't contain the host.
+func LoadReplayRequests(path string) ([]ReplayRequest, error) {
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if strings.EqualFold(filepath.Ext(path), ".har") {
+		return loadHarRequests(raw)
+	}
+	if requests, err := loadHarRequests(raw); err == nil && len(requests) > 0 {
+		return requests, nil
+	}
+	return loadAccessLogRequests(raw)
+}
+
+func loadHarRequests(raw []byte) ([]ReplayRequest, error) {
+	har := harFile{}
+	if err := json.Unmarshal(raw, &har); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	out := make([]ReplayRequest, 0)
+	for _, entry := range har.Log.Entries {
+		requestURL, err := url.Parse(entry.Request.URL)
+		if err != nil || requestURL.Host == "" {
+			continue
+		}
+		headers := make(map[string]string)
+		for _, header := range entry.Request.Headers {
+			headers[header.Name] = header.Value
+		}
+		out = append(out, ReplayRequest{
+			Method:   entry.Request.Method,
+			Scheme:   requestURL.Scheme,
+			Host:     requestURL.Host,
+			URI:      requestURL.RequestURI(),
+			Headers:  headers,
+			Body:     entry.Request.PostData.Text,
+			Status:   entry.Response.Status,
+			Duration: entry.Time / 1000,
+		})
+	}
+	return out, nil
+}
+
+func loadAccessLogRequests(raw []byte) ([]ReplayRequest, error) {
+	out := make([]ReplayRequest, 0)
+	err := nginxReadRequests(bufio.NewReader(bytes.NewReader(raw)), NginxRequestFilter{}, func(r NginxRequest) {
+		out = append(out, ReplayRequest{
+			Method:   r.Method,
+			Host:     r.Host,
+			URI:      r.URI,
+			Status:   r.Status,
+			Duration: r.RequestTime,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(out) > 0 {
+		return out, nil
+	}
+	for _, line := range strings.Split(string(raw), "\n") {
+		match := replayCombinedLogRegex.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		status, _ := strconv.Atoi(match[3])
+		out = append(out, ReplayRequest{
+			Method: match[1],
+			URI:    match[2],
+			Status: status,
+		})
+	}
+	return out, nil
+}
+
+// replayHosts maps the host names of the project's routes, and the router's host names themselves, to the
+// host names the router serves them on.
+func (p *Project) replayHosts() map[string]string {
+	out := make(map[string]string)
+	for _, route := range p.Routes {
+		routeURL, err := url.Parse(ProjectDefaultHostName(p, route.Path))
+		if err != nil {
+			continue
+		}
+		originalURL, err := url.Parse(ProjectDefaultHostName(p, route.OriginalURL))
+		if err != nil {
+			continue
+		}
+		out[routeURL.Host] = routeURL.Host
+		if route.Path != route.OriginalURL {
+			out[originalURL.Host] = routeURL.Host
+		}
+	}
+	return out
+}
+
+// replayScheme returns the scheme the router serves given host with, https unless the host only has http routes.
+func (p *Project) replayScheme(host string) string {
+	scheme := ""
+	for _, route := range p.Routes {
+		routeURL, err := url.Parse(ProjectDefaultHostName(p, route.Path))
+		if err != nil || routeURL.Host != host {
+			continue
+		}
+		if routeURL.Scheme == "https" {
+			return "https"
+		}
+		scheme = routeURL.Scheme
+	}
+	if scheme == "" {
+		return "https"
+	}
+	return scheme
+}
+
+// ReplayHost returns the router host name for given recorded host and false if the host isn't one of the
+// project's route hosts or defaultHost, like third party hosts in a HAR file.
+// Recorded requests without a host go to defaultHost.
+func (p *Project) ReplayHost(host string, defaultHost string) (string, bool) {
+	hosts := p.replayHosts()
+	if localHost, ok := hosts[host]; ok {
+		return localHost, true
+	}
+	if host != "" && host != defaultHost {
+		return "", false
+	}
+	if localHost, ok := hosts[defaultHost]; ok {
+		return localHost, true
+	} else if defaultHost != "" {
+		return defaultHost, true
+	}
+	// fall back to the host of the {default} route, or the first upstream route
+	fallbackHosts := make([]string, 0)
+	for _, route := range p.Routes {
+		if route.Type != "upstream" || route.Path == route.OriginalURL {
+			continue
+		}
+		routeURL, err := url.Parse(ProjectDefaultHostName(p, route.Path))
+		if err != nil {
+			continue
+		}
+		if originalURL, err := url.Parse(route.OriginalURL); err == nil && originalURL.Host == "__PID__.default" {
+			return routeURL.Host, true
+		}
+		fallbackHosts = append(fallbackHosts, routeURL.Host)
+	}
+	if len(fallbackHosts) > 0 {
+		sort.Strings(fallbackHosts)
+		return fallbackHosts[0], true
+	}
+	return defaultHostName, true
+}
+
+// Replay sends given recorded request to the router with its host rewritten to the router's host name.
+// Redirects aren't followed so their status can be compared.
+func (p *Project) Replay(r ReplayRequest, defaultHost string) ReplayResult {
+	config, err := LoadConfig()
+	if err != nil {
+		return ReplayResult{Error: err.Error()}
+	}
+	host, ok := p.ReplayHost(r.Host, defaultHost)
+	if !ok {
+		return ReplayResult{
+			Method:         r.Method,
+			URL:            fmt.Sprintf("%s://%s%s", r.Scheme, r.Host, r.URI),
+			ExpectedStatus: r.Status,
+			Error:          errors.WithMessage(ErrReplayHostUnknown, r.Host).Error(),
+		}
+	}
+	scheme := r.Scheme
+	if scheme == "" {
+		scheme = p.replayScheme(host)
+	}
+	port := config.RouterHTTPS
+	if scheme == "http" {
+		port = config.RouterHTTP
+	}
+	result := ReplayResult{
+		Method:           r.Method,
+		URL:              fmt.Sprintf("%s://%s%s", scheme, host, r.URI),
+		ExpectedStatus:   r.Status,
+		ExpectedDuration: r.Duration,
+	}
+	var body io.Reader
+	if r.Body != "" {
+		body = strings.NewReader(r.Body)
+	}
+	req, err := http.NewRequest(r.Method, fmt.Sprintf("%s://127.0.0.1:%d%s", scheme, port, r.URI), body)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	for name, value := range r.Headers {
+		skip := strings.HasPrefix(name, ":")
+		for _, skipHeader := range replaySkipHeaders {
+			if strings.EqualFold(name, skipHeader) {
+				skip = true
+				break
+			}
+		}
+		if !skip {
+			req.Header.Set(name, value)
+		}
+	}
+	req.Host = host
+	client := &http.Client{
+		Timeout: replayTimeout,
+		Transport: &http.Transport{
+			DisableKeepAlives: true,
+			// the router's certificate is self signed
+			TLSClientConfig: &tls.Config{ServerName: strings.Split(host, ":")[0], InsecureSkipVerify: true},
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+	result.Duration = time.Since(start).Seconds()
+	result.Status = resp.StatusCode
+	return result
+}
+
+// isReplaySafe returns true if given request method doesn't change state.
+func isReplaySafe(method string) bool {
+	switch strings.ToUpper(method) {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+// ReplayRequests replays given requests against the router one after another, calling given callback with each
+// result. Requests that could change state are skipped unless allMethods is true, requests for hosts that aren't
+// the project's are always skipped. It returns the number of requests skipped because of their host.
+func (p *Project) ReplayRequests(requests []ReplayRequest, defaultHost string, allMethods bool, callback func(ReplayResult)) int {
+	skippedHosts := 0
+	for _, r := range requests {
+		if !allMethods && !isReplaySafe(r.Method) {
+			continue
+		}
+		if _, ok := p.ReplayHost(r.Host, defaultHost); !ok {
+			skippedHosts++
+			continue
+		}
+		callback(p.Replay(r, defaultHost))
+	}
+	return skippedHosts
+}
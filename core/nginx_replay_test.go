@@ -0,0 +1,40 @@
+package core
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestReplayHost(t *testing.T) {
+	setupNginxRoutesTest(t, "")
+	p, err := LoadProject(filepath.Join(nginxRoutesTestData, "upstream"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defaultHost, ok := p.ReplayHost("", "")
+	if !ok || defaultHost == "" {
+		t.Fatalf("expected requests without a host to go to the default route, got '%s'", defaultHost)
+	}
+	for host, localHost := range p.replayHosts() {
+		if out, ok := p.ReplayHost(host, ""); !ok || out != localHost {
+			t.Errorf("expected %s for route host %s, got '%s'", localHost, host, out)
+		}
+	}
+	tests := []struct {
+		host        string
+		defaultHost string
+		expected    string
+		ok          bool
+	}{
+		{host: "fonts.googleapis.com", ok: false},
+		{host: "cdn.example.com", defaultHost: "www.example.com", ok: false},
+		{host: "www.example.com", defaultHost: "www.example.com", expected: "www.example.com", ok: true},
+		{host: "", defaultHost: "www.example.com", expected: "www.example.com", ok: true},
+	}
+	for _, tt := range tests {
+		out, ok := p.ReplayHost(tt.host, tt.defaultHost)
+		if ok != tt.ok || out != tt.expected {
+			t.Errorf("expected ('%s', %v) for %s, got ('%s', %v)", tt.expected, tt.ok, tt.host, out, ok)
+		}
+	}
+}
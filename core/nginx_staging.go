@@ -0,0 +1,173 @@
+package core
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/output"
+)
+
+const nginxTestCmd = "sudo {BREW_PATH}/opt/nginx/bin/nginx -t -c {STAGING_CONF_FILE} -p {BREW_PATH}/opt/nginx/ -e {LOG_PATH}/nginx_error.log"
+
+// NginxConfigs returns the generated router config files for given project by path.
+func NginxConfigs(proj *Project) (map[string]string, error) {
+	out := make(map[string]string)
+	nginxRoutes, err := proj.GenerateNginxRoutes()
+	if err != nil {
+		return out, err
+	}
+	out[NginxRouteConfigPath(proj)] = nginxRoutes
+	for _, app := range proj.Apps {
+		nginxApp, err := proj.GenerateNginxApp(app)
+		if err != nil {
+			return out, err
+		}
+		out[NginxAppConfigPath(proj, app)] = nginxApp
+	}
+	return out, nil
+}
+
+// NginxConfigPaths returns the paths of given config files in a stable order.
+func NginxConfigPaths(configs map[string]string) []string {
+	out := make([]string, 0)
+	for path := range configs {
+		out = append(out, path)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// nginxStagingPath returns the path to the directory router config is tested in.
+func nginxStagingPath() string {
+	return filepath.Join(GetDir(TempDir), "nginx_staging")
+}
+
+// nginxTest copies the router config to a staging directory, applies given changes and runs nginx -t against it.
+// Changes map config paths to their new contents, an empty content deletes the file.
+func nginxTest(changes map[string]string) error {
+	nginx := NginxService()
+	if _, err := os.Stat(filepath.Join(GetDir(BrewDir), "opt", "nginx", "bin", "nginx")); os.IsNotExist(err) {
+		output.LogWarn("Nginx is not installed, skip config test.")
+		return nil
+	}
+	if _, err := os.Stat(nginx.ConfigPath()); os.IsNotExist(err) {
+		if err := nginx.GenerateConfigFile(); err != nil {
+			return err
+		}
+	}
+	done := output.Duration("Test router config.")
+	stagingPath := nginxStagingPath()
+	if err := os.RemoveAll(stagingPath); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := os.MkdirAll(stagingPath, mkdirPerm); err != nil {
+		return errors.WithStack(err)
+	}
+	defer os.RemoveAll(stagingPath)
+	// copy current config, pointing includes at the staging directory
+	files, err := ioutil.ReadDir(GetDir(ConfDir))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	for _, f := range files {
+		if f.IsDir() || !strings.HasPrefix(f.Name(), "nginx") {
+			continue
+		}
+		raw, err := ioutil.ReadFile(filepath.Join(GetDir(ConfDir), f.Name()))
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		contents := strings.ReplaceAll(string(raw), GetDir(ConfDir), stagingPath)
+		if err := ioutil.WriteFile(filepath.Join(stagingPath, f.Name()), []byte(contents), 0655); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	for path, contents := range changes {
+		stagingFilePath := filepath.Join(stagingPath, filepath.Base(path))
+		if contents == "" {
+			os.Remove(stagingFilePath)
+			continue
+		}
+		if err := ioutil.WriteFile(stagingFilePath, []byte(contents), 0655); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	// nginx_fastcgi_params.normal is included relative to the main config
+	fastcgiParamsPath := filepath.Join(stagingPath, "nginx_fastcgi_params.normal")
+	if _, err := os.Stat(fastcgiParamsPath); os.IsNotExist(err) {
+		raw, err := ioutil.ReadFile(filepath.Join(GetDir(AppDir), "conf", "nginx_fastcgi_params.normal"))
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if err := ioutil.WriteFile(fastcgiParamsPath, raw, 0655); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	cmdStr := strings.ReplaceAll(
+		nginx.injectCommandParams(nginxTestCmd),
+		"{STAGING_CONF_FILE}",
+		filepath.Join(stagingPath, filepath.Base(nginx.ConfigPath())),
+	)
+	cmd := exec.Command("bash", "-c", cmdStr)
+	cmd.Env = brewEnv()
+	cmd.Stdin = os.Stdin
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return errors.WithStack(errors.WithMessage(
+			ErrNginxConfigInvalid,
+			strings.TrimSpace(strings.ReplaceAll(string(out), stagingPath, GetDir(ConfDir))),
+		))
+	}
+	done()
+	return nil
+}
+
+// nginxApply tests given changes, writes them to the config directory and reloads the router if it's running.
+// The previous config is restored when the reload fails.
+func nginxApply(changes map[string]string) error {
+	if err := nginxTest(changes); err != nil {
+		return err
+	}
+	// backup current config
+	backup := make(map[string]string)
+	for path := range changes {
+		raw, err := ioutil.ReadFile(path)
+		if err != nil && !os.IsNotExist(err) {
+			return errors.WithStack(err)
+		}
+		backup[path] = string(raw)
+	}
+	writeConfigs := func(configs map[string]string) error {
+		for path, contents := range configs {
+			if contents == "" {
+				os.Remove(path)
+				continue
+			}
+			if err := ioutil.WriteFile(path, []byte(contents), 0655); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+		return nil
+	}
+	if err := writeConfigs(changes); err != nil {
+		writeConfigs(backup)
+		return err
+	}
+	nginx := NginxService()
+	if !nginx.IsRunning() {
+		return nil
+	}
+	if err := nginx.Reload(); err != nil {
+		output.Warn(fmt.Sprintf("Router reload failed, restore previous config. (%s)", err.Error()))
+		if err := writeConfigs(backup); err != nil {
+			return err
+		}
+		return err
+	}
+	return nil
+}
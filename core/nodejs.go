@@ -0,0 +1,160 @@
+package core
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/def"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/output"
+)
+
+const nodeDefaultVersion = "lts/*"
+const nodeVersionFilename = ".nvmrc"
+
+// nodeVersionDependencies are dependencies.nodejs entries that select the Node.js version instead of a package.
+var nodeVersionDependencies = []string{"node", "nodejs"}
+
+// nodePackageManagers are dependencies.nodejs entries that are installed with corepack instead of npm.
+var nodePackageManagers = []string{"yarn", "pnpm"}
+
+var nodeVersionConstraintRegex = regexp.MustCompile(`[0-9]+(\.[0-9]+)*`)
+
+// nodeBinDirs caches resolved Node.js bin directories by requested version.
+var nodeBinDirs = map[string]string{}
+
+// nodeUsed returns true if the application needs Node.js, either from its type, dependencies.nodejs or .nvmrc.
+func nodeUsed(d *def.App) bool {
+	if d.GetTypeName() == "nodejs" || len(d.Dependencies.NodeJS) > 0 {
+		return true
+	}
+	_, err := os.Stat(filepath.Join(d.Path, nodeVersionFilename))
+	return err == nil
+}
+
+// nodeVersion returns the Node.js version requested by the application.
+// The version comes from a nodejs:<ver> type, a node entry in dependencies.nodejs or .nvmrc in the application root.
+func nodeVersion(d *def.App) string {
+	if d.GetTypeName() == "nodejs" {
+		typeSplit := strings.SplitN(d.Type, ":", 2)
+		if len(typeSplit) == 2 && typeSplit[1] != "" {
+			return typeSplit[1]
+		}
+	}
+	for _, name := range nodeVersionDependencies {
+		if version := nodeVersionConstraintRegex.FindString(d.Dependencies.NodeJS[name]); version != "" {
+			return version
+		}
+	}
+	if f, err := os.Open(filepath.Join(d.Path, nodeVersionFilename)); err == nil {
+		defer f.Close()
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			line := strings.TrimSpace(strings.SplitN(scanner.Text(), "#", 2)[0])
+			if line != "" {
+				return line
+			}
+		}
+	}
+	return nodeDefaultVersion
+}
+
+// nodePackages returns the dependencies.nodejs entries that should be installed with npm.
+func nodePackages(d *def.App) map[string]string {
+	out := make(map[string]string)
+	for name, version := range d.Dependencies.NodeJS {
+		if sliceContains(nodeVersionDependencies, name) || sliceContains(nodePackageManagers, name) {
+			continue
+		}
+		out[name] = version
+	}
+	return out
+}
+
+// nodePackageManagerVersions returns the package managers listed in dependencies.nodejs and their version constraints.
+func nodePackageManagerVersions(d *def.App) map[string]string {
+	out := make(map[string]string)
+	for _, name := range nodePackageManagers {
+		version, ok := d.Dependencies.NodeJS[name]
+		if !ok {
+			continue
+		}
+		if version == "" || version == "*" {
+			version = "latest"
+		}
+		out[name] = version
+	}
+	return out
+}
+
+// nodeCorepackHome returns the corepack directory of the application, so package manager versions aren't shared.
+func (p *Project) nodeCorepackHome(d *def.App) string {
+	return filepath.Join(p.DepInstallPath(d), "corepack")
+}
+
+// nodePackageManagerInstall installs the package managers listed in dependencies.nodejs with corepack
+// at the newest version matching their constraint and links them in to given bin directory.
+func (p *Project) nodePackageManagerInstall(d *def.App, binDir string) error {
+	for name, version := range nodePackageManagerVersions(d) {
+		done := output.Duration(fmt.Sprintf("Install %s %s.", name, version))
+		if err := p.Command(d, fmt.Sprintf(
+			"PM_VERSION=$(npm view '%[1]s@%[2]s' version | tail -n 1 | awk '{print $NF}' | tr -d \"'\") && "+
+				"corepack enable --install-directory %[3]s %[1]s && corepack prepare \"%[1]s@$PM_VERSION\" --activate",
+			name, version, binDir,
+		)); err != nil {
+			return err
+		}
+		done()
+	}
+	return nil
+}
+
+// nvmScriptPath returns the path to nvm.sh.
+func nvmScriptPath() string {
+	return filepath.Join(GetDir(BrewDir), "opt", "nvm", "nvm.sh")
+}
+
+// nvmCommand runs given nvm command and returns its output.
+func nvmCommand(version string, nvmCmd string) ([]byte, error) {
+	cmd := exec.Command(
+		"bash", "-c",
+		fmt.Sprintf("source %s --no-use && nvm %s \"$PBREW_NODE_VERSION\"", nvmScriptPath(), nvmCmd),
+	)
+	cmd.Env = append(brewEnv(), "PBREW_NODE_VERSION="+version)
+	return cmd.Output()
+}
+
+// nodeInstall installs given Node.js version with nvm.
+func nodeInstall(version string) error {
+	done := output.Duration(fmt.Sprintf("Install Node.js %s.", version))
+	if out, err := nvmCommand(version, "install"); err != nil {
+		output.LogInfo(string(out))
+		return errors.WithStack(errors.WithMessage(ErrNodeVersionNotFound, version))
+	}
+	done()
+	return nil
+}
+
+// nodeBinDir returns the bin directory of given Node.js version, installing it when needed.
+func nodeBinDir(version string) (string, error) {
+	if binDir, ok := nodeBinDirs[version]; ok {
+		return binDir, nil
+	}
+	out, err := nvmCommand(version, "which")
+	if err != nil {
+		if err := nodeInstall(version); err != nil {
+			return "", err
+		}
+		if out, err = nvmCommand(version, "which"); err != nil {
+			return "", errors.WithStack(errors.WithMessage(ErrNodeVersionNotFound, version))
+		}
+	}
+	binDir := filepath.Dir(strings.TrimSpace(string(out)))
+	nodeBinDirs[version] = binDir
+	return binDir, nil
+}
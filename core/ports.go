@@ -86,6 +86,9 @@ func (p PortMap) assignPort(name string) (int, error) {
 func (p PortMap) ServicePort(s *Service) (int, error) {
 	if s.Multiple && s.project != nil {
 		// multi-instance service
+		if appName := s.appInstanceName(); appName != "" {
+			return p.assignPort("s-" + s.BrewAppName() + "-" + s.project.Name + "-" + appName)
+		}
 		return p.assignPort("s-" + s.BrewAppName() + "-" + s.project.Name)
 	} else if s.BrewAppName() != "" {
 		return p.assignPort("s-" + s.BrewAppName())
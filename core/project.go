@@ -3,7 +3,6 @@ package core
 import (
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
 	"time"
@@ -22,11 +21,15 @@ type Project struct {
 	Path            string        `json:"path"`
 	Name            string        `json:"name"`
 	DefaultDomain   string        `json:"default_domain"`
+	LocalDomain     string        `json:"local_domain"`
 	Apps            []*def.App    `json:"-"`
 	Services        []def.Service `json:"-"`
 	Routes          []def.Route   `json:"-"`
 	NoMounts        bool          `json:"-"`
 	UsePbrewBottles bool          `json:"-"`
+	UpdateDeps      bool          `json:"-"`
+	ComposerDev     bool          `json:"-"`
+	BypassVarnish   bool          `json:"-"`
 }
 
 func findProjectRoot(path string) (string, error) {
@@ -54,8 +57,6 @@ func findProjectRoot(path string) (string, error) {
 func LoadProject(projPath string) (*Project, error) {
 	var err error
 
-	defaultDomain, _ := exec.Command("bash", "-c", "platform project:info -- default_domain").Output()
-
 	projPath, err = findProjectRoot(projPath)
 	if err != nil {
 		return nil, errors.WithStack(err)
@@ -70,6 +71,12 @@ func LoadProject(projPath string) (*Project, error) {
 		}
 		apps = append(apps, app)
 	}
+	multiApps, err := parseApplicationsYaml(projPath)
+	if err != nil {
+		return nil, err
+	}
+	apps = append(apps, multiApps...)
+	checkAppNames(apps)
 	serviceYamlFullPaths := make([]string, 0)
 	for _, f := range serviceYamlFilenames {
 		serviceYamlFullPaths = append(serviceYamlFullPaths, filepath.Join(projPath, f))
@@ -86,7 +93,15 @@ func LoadProject(projPath string) (*Project, error) {
 	if err != nil {
 		return nil, err
 	}
-	routes, err = def.ExpandRoutes(routes, "localtest.me")
+	localDomain, err := projectLocalDomain(projPath)
+	if err != nil {
+		return nil, err
+	}
+	defaultDomain, err := projectDefaultDomain(projPath)
+	if err != nil {
+		output.Warn(err.Error())
+	}
+	routes, err = def.ExpandRoutes(routes, localDomain)
 	if err != nil {
 		return nil, err
 	}
@@ -97,7 +112,8 @@ func LoadProject(projPath string) (*Project, error) {
 	return &Project{
 		Path:     projPath,
 		Name:     strings.ToLower(filepath.Base(projPath)),
-		DefaultDomain: string(defaultDomain),
+		DefaultDomain: defaultDomain,
+		LocalDomain: localDomain,
 		Apps:     apps,
 		Services: services,
 		Routes:   routes,
@@ -0,0 +1,184 @@
+package core
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/def"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/output"
+	"gopkg.in/yaml.v2"
+)
+
+// applicationsYamlFilename is the file that defines several apps of a project at once.
+const applicationsYamlFilename = ".platform/applications.yaml"
+
+// appScanMaxDepth is the number of directories below the project root searched for app yaml files.
+const appScanMaxDepth = 5
+
+// appScanSkipDirs are directories that never contain app yaml files.
+var appScanSkipDirs = []string{"node_modules", "vendor"}
+
+type applicationsYamlApp struct {
+	Name string
+	Root string
+	raw  []byte
+}
+
+// applicationsYamlApps returns the app definitions in .platform/applications.yaml of the project at given path.
+// Apps can be a list of definitions with a name or a map of definitions by name.
+func applicationsYamlApps(projPath string) ([]applicationsYamlApp, error) {
+	out := make([]applicationsYamlApp, 0)
+	raw, err := ioutil.ReadFile(filepath.Join(projPath, applicationsYamlFilename))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return out, nil
+		}
+		return out, errors.WithStack(err)
+	}
+	addApp := func(name string, value interface{}) error {
+		app, ok := value.(map[interface{}]interface{})
+		if !ok {
+			return nil
+		}
+		if appName, ok := yamlLookup(app, "name").(string); ok && appName != "" {
+			name = appName
+		}
+		root, _ := yamlLookup(app, "source", "root").(string)
+		appRaw, err := yaml.Marshal(app)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		out = append(out, applicationsYamlApp{
+			Name: name,
+			Root: filepath.Join(projPath, strings.TrimLeft(root, "/")),
+			raw:  appRaw,
+		})
+		return nil
+	}
+	var doc interface{}
+	if err := yaml.Unmarshal(raw, &doc); err != nil {
+		return out, errors.WithStack(err)
+	}
+	switch doc := doc.(type) {
+	case []interface{}:
+		{
+			for _, app := range doc {
+				if err := addApp("", app); err != nil {
+					return out, err
+				}
+			}
+			break
+		}
+	case map[interface{}]interface{}:
+		{
+			// a map slice keeps the apps in file order, its nested maps are map slices
+			// too so the definitions are taken from the plain map
+			order := yaml.MapSlice{}
+			if err := yaml.Unmarshal(raw, &order); err != nil {
+				return out, errors.WithStack(err)
+			}
+			for _, item := range order {
+				if err := addApp(fmt.Sprintf("%v", item.Key), doc[item.Key]); err != nil {
+					return out, err
+				}
+			}
+			break
+		}
+	}
+	return out, nil
+}
+
+// parseApplicationsYaml parses the apps in .platform/applications.yaml of the project at given path.
+// The path of every app is its source.root.
+func parseApplicationsYaml(projPath string) ([]*def.App, error) {
+	out := make([]*def.App, 0)
+	apps, err := applicationsYamlApps(projPath)
+	if err != nil {
+		return out, err
+	}
+	for _, app := range apps {
+		d, err := def.ParseAppYamls([][]byte{app.raw}, nil)
+		if err != nil {
+			return out, errors.WithStack(err)
+		}
+		if app.Name != "" {
+			d.Name = app.Name
+		}
+		d.Path = app.Root
+		out = append(out, d)
+	}
+	return out, nil
+}
+
+// applicationsYamlRaw returns the raw definition of given app from .platform/applications.yaml.
+func applicationsYamlRaw(d *def.App) map[interface{}]interface{} {
+	out := make(map[interface{}]interface{})
+	projPath, err := findProjectRoot(d.Path)
+	if err != nil {
+		return out
+	}
+	apps, err := applicationsYamlApps(projPath)
+	if err != nil {
+		output.Warn(err.Error())
+		return out
+	}
+	for _, app := range apps {
+		if app.Name != d.Name {
+			continue
+		}
+		if err := yaml.Unmarshal(app.raw, &out); err != nil {
+			output.Warn(err.Error())
+		}
+		break
+	}
+	return out
+}
+
+// checkAppNames warns about apps that share a name, only one of them can be routed to.
+func checkAppNames(apps []*def.App) {
+	seen := make(map[string]string)
+	for _, app := range apps {
+		if path, ok := seen[app.Name]; ok {
+			output.Warn(fmt.Sprintf("App name '%s' is used by both %s and %s.", app.Name, path, app.Path))
+			continue
+		}
+		seen[app.Name] = app.Path
+	}
+}
+
+// AppRoutes returns the routes, by their original URL, that are served by given app.
+func (p *Project) AppRoutes(d *def.App) []string {
+	out := make([]string, 0)
+	for _, route := range p.Routes {
+		if route.Type != "upstream" || route.Path != route.OriginalURL {
+			continue
+		}
+		target := p.MatchRelationshipToService(route.Upstream)
+		// routes through varnish are served by the app behind it
+		if service, ok := target.(def.Service); ok && service.GetTypeName() == "varnish" {
+			target = p.varnishBackend(service)
+		}
+		if app, ok := target.(*def.App); ok && app == d {
+			out = append(out, ProjectDefaultHostName(p, route.OriginalURL))
+		}
+	}
+	return out
+}
+
+// UnmatchedRoutes returns the upstream routes, by their original URL, whose upstream doesn't match an app or service.
+func (p *Project) UnmatchedRoutes() []string {
+	out := make([]string, 0)
+	for _, route := range p.Routes {
+		if route.Type != "upstream" || route.Path != route.OriginalURL {
+			continue
+		}
+		if p.MatchRelationshipToService(route.Upstream) == nil {
+			out = append(out, fmt.Sprintf("%s (%s)", ProjectDefaultHostName(p, route.OriginalURL), route.Upstream))
+		}
+	}
+	return out
+}
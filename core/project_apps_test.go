@@ -0,0 +1,89 @@
+package core
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+const appsTestData = "testdata/apps"
+
+func TestScanPlatformAppYamlNested(t *testing.T) {
+	projPath, err := filepath.Abs(filepath.Join(appsTestData, "nested"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := [][]string{
+		{".platform.app.yaml"},
+		{"backend/.platform.app.yaml", "backend/.platform.app.pcc.yaml"},
+		{"frontend/web/.platform.app.yaml"},
+	}
+	appPaths := scanPlatformAppYaml(projPath, false)
+	if len(appPaths) != len(expected) {
+		t.Fatalf("expected %d apps, got %d: %v", len(expected), len(appPaths), appPaths)
+	}
+	for i := range expected {
+		if len(appPaths[i]) != len(expected[i]) {
+			t.Fatalf("expected %v for app %d, got %v", expected[i], i, appPaths[i])
+		}
+		for j := range expected[i] {
+			if appPaths[i][j] != filepath.Join(projPath, expected[i][j]) {
+				t.Errorf("expected %s for app %d, got %s", expected[i][j], i, appPaths[i][j])
+			}
+		}
+	}
+	// overrides are dropped, not turned in to apps of their own
+	for _, appPath := range scanPlatformAppYaml(projPath, true) {
+		if len(appPath) != 1 {
+			t.Errorf("expected no overrides, got %v", appPath)
+		}
+	}
+}
+
+func TestLoadProjectApps(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected map[string]string
+	}{
+		{name: "nested", expected: map[string]string{"root": "", "backend": "backend", "frontend": "frontend/web"}},
+		{name: "multi", expected: map[string]string{"web": "web", "api": "api"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			projPath, err := filepath.Abs(filepath.Join(appsTestData, tt.name))
+			if err != nil {
+				t.Fatal(err)
+			}
+			p, err := LoadProject(projPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(p.Apps) != len(tt.expected) {
+				t.Fatalf("expected %d apps, got %d", len(tt.expected), len(p.Apps))
+			}
+			for _, app := range p.Apps {
+				relPath, ok := tt.expected[app.Name]
+				if !ok {
+					t.Errorf("unexpected app %s at %s", app.Name, app.Path)
+					continue
+				}
+				if app.Path != filepath.Join(projPath, relPath) {
+					t.Errorf("expected app %s at %s, got %s", app.Name, filepath.Join(projPath, relPath), app.Path)
+				}
+			}
+		})
+	}
+}
+
+func TestApplicationsYamlAppsOrder(t *testing.T) {
+	apps, err := applicationsYamlApps(filepath.Join(appsTestData, "multi"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, 0)
+	for _, app := range apps {
+		names = append(names, app.Name)
+	}
+	if len(names) != 2 || names[0] != "web" || names[1] != "api" {
+		t.Errorf("expected apps in file order [web api], got %v", names)
+	}
+}
@@ -0,0 +1,69 @@
+package core
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// ProjectConfigFile is the name of the per project configuration file in the project root.
+const ProjectConfigFile = ".pbrew.yaml"
+
+const defaultLocalDomain = "localtest.me"
+
+// ProjectConfig defines per project configuration that overrides the user configuration.
+type ProjectConfig struct {
+	LocalDomain   string `yaml:"local_domain"`
+	DefaultDomain string `yaml:"default_domain"`
+}
+
+// LoadProjectConfig returns the configuration in the root of the project at given path.
+func LoadProjectConfig(projPath string) (ProjectConfig, error) {
+	config := ProjectConfig{}
+	raw, err := ioutil.ReadFile(filepath.Join(projPath, ProjectConfigFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return config, nil
+		}
+		return config, errors.WithStack(err)
+	}
+	if err := yaml.Unmarshal(raw, &config); err != nil {
+		return config, errors.WithStack(err)
+	}
+	return config, nil
+}
+
+// projectLocalDomain returns the domain that route host names are expanded with for the project at given path.
+func projectLocalDomain(projPath string) (string, error) {
+	projConfig, err := LoadProjectConfig(projPath)
+	if err != nil {
+		return defaultLocalDomain, err
+	}
+	if projConfig.LocalDomain != "" {
+		return projConfig.LocalDomain, nil
+	}
+	config, err := LoadConfig()
+	if err != nil {
+		return defaultLocalDomain, err
+	}
+	if config.LocalDomain == "" {
+		return defaultLocalDomain, nil
+	}
+	return config.LocalDomain, nil
+}
+
+// projectDefaultDomain returns the default domain for the project at given path, from the project config or
+// the domain stored by p:sync-domain.
+func projectDefaultDomain(projPath string) (string, error) {
+	projConfig, err := LoadProjectConfig(projPath)
+	if err != nil {
+		return "", err
+	}
+	if projConfig.DefaultDomain != "" {
+		return projConfig.DefaultDomain, nil
+	}
+	return ProjectDomainGet(projPath)
+}
@@ -106,6 +106,18 @@ func (p *Project) GenerateRelationships(d interface{}) []map[string]interface{}
 					rel["rel"] = "redis"
 					rel["scheme"] = "redis"
 				}
+				if serviceOverride != nil {
+					rel = serviceOverride.Relationship()
+					rel["rel"] = d.GetTypeName()
+				} else if brewService != nil && brewService.IsMongoDB() {
+					rel["path"] = brewService.mongoDBDefaultDatabase()
+					rel["username"] = brewService.MongoDBUser()
+					rel["password"] = brewService.MongoDBPassword()
+					rel["scheme"] = "mongodb"
+					rel["query"] = map[string]interface{}{
+						"is_master": true,
+					}
+				}
 				out = append(out, rel)
 			}
 			return out
@@ -156,27 +168,34 @@ func (p *Project) MapRelationships(d interface{}) map[string][]map[string]interf
 // MatchRelationshipToService matches a given relationship to its service def.
 func (p *Project) MatchRelationshipToService(rel string) interface{} {
 	relSplit := strings.Split(rel, ":")
+	endpoint := "http"
+	if len(relSplit) > 1 {
+		endpoint = relSplit[1]
+	}
 	// look for service match
 	for _, service := range p.Services {
+		// varnish sits between the router and its backend apps unless bypassed
+		if service.Name == relSplit[0] && service.GetTypeName() == "varnish" {
+			if p.BypassVarnish {
+				return p.varnishBackend(service)
+			}
+			return service
+		}
 		if service.Name == relSplit[0] {
 			serviceRels := p.GenerateRelationships(service)
 			for _, serviceRel := range serviceRels {
-				if strings.HasSuffix(serviceRel["rel"].(string), relSplit[1]) {
+				if strings.HasSuffix(serviceRel["rel"].(string), endpoint) {
 					return service
 				}
 			}
 		}
 	}
-	// map varnish to first app
-	if relSplit[0] == "varnish" {
-		return p.Apps[0]
-	}
-	// look for app match
+	// look for app match, the router reaches every app over http
 	for _, service := range p.Apps {
 		if service.Name == relSplit[0] {
 			serviceRels := p.GenerateRelationships(service)
 			for _, serviceRel := range serviceRels {
-				if serviceRel["rel"] == relSplit[1] {
+				if serviceRel["rel"] == endpoint || endpoint == "http" {
 					return service
 				}
 			}
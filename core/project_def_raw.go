@@ -0,0 +1,107 @@
+package core
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/def"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/output"
+	"gopkg.in/yaml.v2"
+)
+
+// appYamlRaw returns the raw app yaml for keys that aren't part of the app definition.
+// Top level keys in override files replace the ones in the main file.
+func appYamlRaw(d *def.App) map[interface{}]interface{} {
+	out := make(map[interface{}]interface{})
+	for _, appYamlFilename := range appYamlFilenames {
+		raw, err := ioutil.ReadFile(filepath.Join(d.Path, appYamlFilename))
+		if err != nil {
+			if !os.IsNotExist(err) {
+				output.Warn(err.Error())
+			}
+			continue
+		}
+		values := make(map[interface{}]interface{})
+		if err := yaml.Unmarshal(raw, &values); err != nil {
+			output.Warn(err.Error())
+			continue
+		}
+		for k, v := range values {
+			out[k] = v
+		}
+	}
+	if len(out) == 0 {
+		return applicationsYamlRaw(d)
+	}
+	return out
+}
+
+// routesYamlRaw returns the raw routes yaml, keyed like def.Route.OriginalURL, for keys that aren't part of the route definition.
+// Routes in override files replace the ones in the main file.
+func (p *Project) routesYamlRaw() map[string]interface{} {
+	out := make(map[string]interface{})
+	for _, routesYamlFilename := range routesYamlFilenames {
+		raw, err := ioutil.ReadFile(filepath.Join(p.Path, routesYamlFilename))
+		if err != nil {
+			if !os.IsNotExist(err) {
+				output.Warn(err.Error())
+			}
+			continue
+		}
+		values := make(map[string]interface{})
+		if err := yaml.Unmarshal(raw, &values); err != nil {
+			output.Warn(err.Error())
+			continue
+		}
+		for k, v := range values {
+			out[strings.ReplaceAll(k, "{default}", "__PID__.default")] = v
+		}
+	}
+	return out
+}
+
+// yamlLookupBool returns the boolean at given path of keys in raw yaml.
+func yamlLookupBool(value interface{}, keys ...string) bool {
+	v, ok := yamlLookup(value, keys...).(bool)
+	return ok && v
+}
+
+// yamlLookup returns the value at given path of keys in raw yaml.
+func yamlLookup(value interface{}, keys ...string) interface{} {
+	for _, key := range keys {
+		switch v := value.(type) {
+		case map[interface{}]interface{}:
+			{
+				value = v[key]
+				break
+			}
+		case map[string]interface{}:
+			{
+				value = v[key]
+				break
+			}
+		default:
+			{
+				return nil
+			}
+		}
+	}
+	return value
+}
+
+// yamlLookupInt returns the integer at given path of keys in raw yaml.
+func yamlLookupInt(value interface{}, keys ...string) int {
+	switch v := yamlLookup(value, keys...).(type) {
+	case int:
+		{
+			return v
+		}
+	case float64:
+		{
+			return int(v)
+		}
+	}
+	return 0
+}
@@ -1,11 +1,23 @@
 package core
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
 	"path/filepath"
 
+	"github.com/pkg/errors"
 	"gitlab.com/contextualcode/platform_cc/v2/pkg/def"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/output"
 )
 
+const depStateFilename = ".pbrew_deps.json"
+const depEcosystemPHP = "php"
+const depEcosystemNode = "nodejs"
+const depEcosystemRuby = "ruby"
+
 // DepInstallPath returns the path that dependencies should be installed to.
 func (p *Project) DepInstallPath(d interface{}) string {
 	switch d := d.(type) {
@@ -16,3 +28,52 @@ func (p *Project) DepInstallPath(d interface{}) string {
 	}
 	return filepath.Join(p.Path, ".global")
 }
+
+// depHash returns a hash of given dependency definitions.
+func depHash(values ...interface{}) string {
+	h := sha256.New()
+	for _, value := range values {
+		raw, _ := json.Marshal(value)
+		h.Write(raw)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// depLoadHashes returns the dependency hashes of the last successful install by ecosystem.
+func (p *Project) depLoadHashes(d *def.App) map[string]string {
+	out := make(map[string]string)
+	raw, err := ioutil.ReadFile(filepath.Join(p.DepInstallPath(d), depStateFilename))
+	if err != nil {
+		return out
+	}
+	if err := json.Unmarshal(raw, &out); err != nil {
+		output.Warn(err.Error())
+	}
+	return out
+}
+
+// depSaveHash stores the dependency hash of a successful install of given ecosystem.
+func (p *Project) depSaveHash(d *def.App, ecosystem string, hash string) error {
+	hashes := p.depLoadHashes(d)
+	hashes[ecosystem] = hash
+	raw, err := json.MarshalIndent(hashes, "", "  ")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if err := os.MkdirAll(p.DepInstallPath(d), 0755); err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(ioutil.WriteFile(filepath.Join(p.DepInstallPath(d), depStateFilename), raw, 0655))
+}
+
+// depIsCurrent returns true if given ecosystem was installed from the same dependencies and its lockfile exists.
+// Dependencies are always reinstalled when UpdateDeps is set.
+func (p *Project) depIsCurrent(d *def.App, ecosystem string, hash string, lockPath string) bool {
+	if p.UpdateDeps {
+		return false
+	}
+	if _, err := os.Stat(lockPath); err != nil {
+		return false
+	}
+	return p.depLoadHashes(d)[ecosystem] == hash
+}
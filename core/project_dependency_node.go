@@ -16,10 +16,9 @@ func (p *Project) depNodeBuildPackageJSON(d interface{}) error {
 	case *def.App:
 		{
 			done := output.Duration("Build package.json.")
-			deps := d.Dependencies
 			packageJSON, err := json.Marshal(map[string]interface{}{
 				"name":         d.Name,
-				"dependencies": deps.NodeJS,
+				"dependencies": nodePackages(d),
 			})
 			if err != nil {
 				return err
@@ -42,18 +41,28 @@ func (p *Project) depNodeBuildPackageJSON(d interface{}) error {
 	return nil
 }
 
-// DepNodeNpmInstall runs composer install for application dependencies.
+// DepNodeNpmInstall runs npm install for application dependencies with the application's Node.js version.
 func (p *Project) DepNodeNpmInstall(d interface{}) error {
 	switch d := d.(type) {
 	case *def.App:
 		{
+			hash := depHash(nodePackages(d), nodeVersion(d), nodePackageManagerVersions(d))
+			lockPath := filepath.Join(p.DepInstallPath(d), "package-lock.json")
+			if p.depIsCurrent(d, depEcosystemNode, hash, lockPath) {
+				output.Info(fmt.Sprintf("Node dependencies for %s are up to date.", d.Name))
+				return nil
+			}
 			done := output.Duration(fmt.Sprintf("Install Node dependencies for %s.", d.Name))
 			if err := p.depNodeBuildPackageJSON(d); err != nil {
 				return err
 			}
+			// npm install reuses package-lock.json for packages that haven't changed
+			if p.UpdateDeps {
+				os.Remove(lockPath)
+			}
 			done2 := output.Duration("Npm install.")
 			if err := p.Command(d, fmt.Sprintf(
-				"source $(brew --prefix nvm)/nvm.sh && npm install %s --prefix %s",
+				"npm install %s --prefix %s",
 				p.DepInstallPath(d), p.DepInstallPath(d),
 			)); err != nil {
 				return err
@@ -62,12 +71,18 @@ func (p *Project) DepNodeNpmInstall(d interface{}) error {
 			os.RemoveAll(nodeBinDir)
 			os.MkdirAll(nodeBinDir, mkdirPerm)
 			if err := p.Command(d, fmt.Sprintf(
-				"cd %s && ln -s ../*/bin/* .",
+				"cd %s && ln -s ../.bin/* .",
 				nodeBinDir,
 			)); err != nil {
 				return err
 			}
+			if err := p.nodePackageManagerInstall(d, nodeBinDir); err != nil {
+				return err
+			}
 			done2()
+			if err := p.depSaveHash(d, depEcosystemNode, hash); err != nil {
+				return err
+			}
 			done()
 		}
 	}
@@ -6,25 +6,38 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
 
+	"github.com/pkg/errors"
 	"gitlab.com/contextualcode/platform_cc/v2/pkg/output"
 
 	"gitlab.com/contextualcode/platform_cc/v2/pkg/def"
 )
 
+// depPHPInstalledFilename is the snapshot of the requirements of the last successful composer install.
+const depPHPInstalledFilename = "composer.installed.json"
+
+// depPHPRequire returns the composer requirements of the application without composer/composer,
+// which selects the composer version instead of being a dependency.
+func depPHPRequire(d *def.App) map[string]string {
+	out := make(map[string]string)
+	for name, version := range d.Dependencies.PHP.Require {
+		if name != "composer/composer" {
+			out[name] = version
+		}
+	}
+	return out
+}
+
+// depPHPBuildComposerJSON writes composer.json.
 func (p *Project) depPHPBuildComposerJSON(d interface{}) error {
 	switch d := d.(type) {
 	case *def.App:
 		{
 			done := output.Duration("Build composer.json.")
-			deps := d.Dependencies
-			if deps.PHP.Require["composer/composer"] == "" {
-				if deps.PHP.Require == nil {
-					deps.PHP.Require = make(map[string]string)
-				}
-				deps.PHP.Require["composer/composer"] = "^1"
-			}
-			composerJSON, err := json.Marshal(deps.PHP)
+			deps := d.Dependencies.PHP
+			deps.Require = depPHPRequire(d)
+			composerJSON, err := json.Marshal(deps)
 			if err != nil {
 				return err
 			}
@@ -33,7 +46,6 @@ func (p *Project) depPHPBuildComposerJSON(d interface{}) error {
 					return err
 				}
 			}
-			os.Remove(filepath.Join(p.DepInstallPath(d), "composer.lock"))
 			if err := ioutil.WriteFile(
 				filepath.Join(p.DepInstallPath(d), "composer.json"),
 				composerJSON,
@@ -47,33 +59,89 @@ func (p *Project) depPHPBuildComposerJSON(d interface{}) error {
 	return nil
 }
 
+// depPHPChanged returns the packages that were added or changed, and the packages that were removed,
+// since the last successful composer install.
+func (p *Project) depPHPChanged(d *def.App) ([]string, []string) {
+	changed := make([]string, 0)
+	removed := make([]string, 0)
+	installed := make(map[string]string)
+	if raw, err := ioutil.ReadFile(filepath.Join(p.DepInstallPath(d), depPHPInstalledFilename)); err == nil {
+		if err := json.Unmarshal(raw, &installed); err != nil {
+			output.Warn(err.Error())
+		}
+	}
+	require := depPHPRequire(d)
+	for name, version := range require {
+		if installed[name] != version {
+			changed = append(changed, name)
+		}
+	}
+	for name := range installed {
+		if _, ok := require[name]; !ok {
+			removed = append(removed, name)
+		}
+	}
+	sort.Strings(changed)
+	sort.Strings(removed)
+	return changed, removed
+}
+
+// depPHPSaveInstalled stores the requirements of a successful composer install.
+func (p *Project) depPHPSaveInstalled(d *def.App) error {
+	raw, err := json.Marshal(depPHPRequire(d))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(ioutil.WriteFile(filepath.Join(p.DepInstallPath(d), depPHPInstalledFilename), raw, 0655))
+}
+
 // DepPHPComposerInstall runs composer install for application dependencies.
 func (p *Project) DepPHPComposerInstall(d interface{}) error {
 	switch d := d.(type) {
 	case *def.App:
 		{
+			if len(depPHPRequire(d)) == 0 {
+				return nil
+			}
+			hash := depHash(d.Dependencies.PHP, composerMajorVersion(d))
+			lockPath := filepath.Join(p.DepInstallPath(d), "composer.lock")
+			if p.depIsCurrent(d, depEcosystemPHP, hash, lockPath) {
+				output.Info(fmt.Sprintf("PHP dependencies for %s are up to date.", d.Name))
+				return nil
+			}
 			done := output.Duration(fmt.Sprintf("Install PHP dependencies for %s.", d.Name))
 			if err := p.depPHPBuildComposerJSON(d); err != nil {
 				return err
 			}
-			done2 := output.Duration("Composer install.")
-			serviceList, err := LoadServiceList()
-			if err != nil {
-				return err
+			// install from the lockfile, only updating packages that changed since the last successful install
+			args := []string{"update"}
+			_, lockErr := os.Stat(lockPath)
+			_, installedErr := os.Stat(filepath.Join(p.DepInstallPath(d), depPHPInstalledFilename))
+			if lockErr == nil && installedErr == nil && !p.UpdateDeps {
+				changed, removed := p.depPHPChanged(d)
+				args = []string{"install"}
+				if len(changed) > 0 {
+					// removed packages are dropped from the lockfile by any update
+					args = append([]string{"update"}, changed...)
+				} else if len(removed) > 0 {
+					args = []string{"update", "--lock"}
+				}
 			}
-			brewService, err := serviceList.MatchDef(d)
+			done2 := output.Duration(fmt.Sprintf("Composer %s.", args[0]))
+			cmdStr, err := p.composerCommand(d, append(args, "-d", p.DepInstallPath(d))...)
 			if err != nil {
 				return err
 			}
-			phpBinPath := filepath.Join(GetDir(BrewDir), "opt", brewService.BrewAppName(), "bin", "php")
-			composerBinPath := filepath.Join(GetDir(BrewDir), "bin", "composer")
-			if err := p.Command(d, fmt.Sprintf(
-				"%s %s install -d %s",
-				phpBinPath, composerBinPath, p.DepInstallPath(d),
-			)); err != nil {
+			if err := p.Command(d, cmdStr); err != nil {
 				return err
 			}
 			done2()
+			if err := p.depPHPSaveInstalled(d); err != nil {
+				return err
+			}
+			if err := p.depSaveHash(d, depEcosystemPHP, hash); err != nil {
+				return err
+			}
 			done()
 		}
 	}
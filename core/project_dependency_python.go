@@ -16,18 +16,21 @@ func (p *Project) depPythonBuildRequirementsTxt(d interface{}) error {
 	case *def.App:
 		{
 			done := output.Duration("Build requirements.txt.")
-			deps := d.Dependencies
-			for pyVersion, pyDeps := range map[int]map[string]string{2: deps.Python2, 3: deps.Python3} {
+			for pyVersion, pyDeps := range pythonDependencies(d) {
+				if len(pyDeps) == 0 {
+					continue
+				}
 				// make dir
-				depPath := filepath.Join(p.DepInstallPath(d), fmt.Sprintf("python%d", pyVersion))
-				if err := os.Mkdir(depPath, 0755); err != nil {
-					if !os.IsExist(err) {
-						return err
-					}
+				depPath := p.pythonVenvPath(d, pyVersion)
+				if err := os.MkdirAll(depPath, 0755); err != nil {
+					return err
 				}
 				// generate requirements
 				out := ""
 				for name, ver := range pyDeps {
+					if name == pythonVersionDependency {
+						continue
+					}
 					out += name
 					if ver != "*" {
 						out += fmt.Sprintf("==%s", ver)
@@ -55,19 +58,42 @@ func (p *Project) DepPythonPipInstall(d interface{}) error {
 			if err := p.depPythonBuildRequirementsTxt(d); err != nil {
 				return err
 			}
-			done2 := output.Duration("Pip install.")
-			for pyMajorVer, pyFullVer := range map[int]string{2: "2.7.18", 3: "3.10.0"} {
-				pipBinPath := filepath.Join(GetDir(HomeDir), ".pyenv", "versions", pyFullVer, "bin", "pip")
-				depPath := filepath.Join(p.DepInstallPath(d), fmt.Sprintf("python%d", pyMajorVer))
-				reqTxtPath := filepath.Join(depPath, "requirements.txt")
+			pyDeps := pythonDependencies(d)
+			for pyMajorVer, pyVer := range pythonVersions(d) {
+				if len(pyDeps[pyMajorVer]) == 0 {
+					continue
+				}
+				ecosystem := fmt.Sprintf("python%d", pyMajorVer)
+				hash := depHash(pyDeps[pyMajorVer], pyVer)
+				depPath := p.pythonVenvPath(d, pyMajorVer)
+				venvPythonPath := filepath.Join(depPath, "bin", "python")
+				lockPath := filepath.Join(p.DepInstallPath(d), fmt.Sprintf("requirements-%s.lock", ecosystem))
+				if _, err := os.Stat(venvPythonPath); err == nil && p.depIsCurrent(d, ecosystem, hash, lockPath) {
+					output.Info(fmt.Sprintf("Python %d dependencies for %s are up to date.", pyMajorVer, d.Name))
+					continue
+				}
+				if err := p.pythonVenvCreate(d, pyMajorVer, pyVer); err != nil {
+					return err
+				}
+				done2 := output.Duration(fmt.Sprintf("Pip install for Python %d.", pyMajorVer))
+				pipArgs := fmt.Sprintf("-r %s", filepath.Join(depPath, "requirements.txt"))
+				if p.UpdateDeps {
+					pipArgs += " --upgrade"
+				} else if _, err := os.Stat(lockPath); err == nil && p.depLoadHashes(d)[ecosystem] == hash {
+					// reinstall the locked versions when the virtualenv was rebuilt
+					pipArgs += fmt.Sprintf(" -c %s", lockPath)
+				}
 				if err := p.Command(d, fmt.Sprintf(
-					"%s install -r %s --prefix %s",
-					pipBinPath, reqTxtPath, depPath,
+					"%s -m pip install %s && %s -m pip freeze > %s",
+					venvPythonPath, pipArgs, venvPythonPath, lockPath,
 				)); err != nil {
 					return err
 				}
+				done2()
+				if err := p.depSaveHash(d, ecosystem, hash); err != nil {
+					return err
+				}
 			}
-			done2()
 			done()
 		}
 	}
@@ -0,0 +1,131 @@
+package core
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/def"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/output"
+)
+
+const rubyBrewName = "ruby"
+const rubyGemSource = "https://rubygems.org"
+
+// rubyDependencies returns dependencies.ruby of the application, it isn't part of the app definition.
+func rubyDependencies(d *def.App) map[string]string {
+	out := make(map[string]string)
+	deps, ok := yamlLookup(appYamlRaw(d), "dependencies", "ruby").(map[interface{}]interface{})
+	if !ok {
+		return out
+	}
+	for name, version := range deps {
+		out[fmt.Sprintf("%v", name)] = fmt.Sprintf("%v", version)
+	}
+	return out
+}
+
+// rubyBinDir returns the directory containing Homebrew's ruby binaries.
+func rubyBinDir() string {
+	return filepath.Join(GetDir(BrewDir), "opt", rubyBrewName, "bin")
+}
+
+// rubyGemHome returns the path that the application's gems are installed to.
+func (p *Project) rubyGemHome(d *def.App) string {
+	return filepath.Join(p.DepInstallPath(d), "ruby")
+}
+
+// rubyEnv returns the environment variables that point ruby at the application's gems.
+func (p *Project) rubyEnv(d *def.App) []string {
+	if len(rubyDependencies(d)) == 0 {
+		return []string{}
+	}
+	return []string{
+		fmt.Sprintf("GEM_HOME=%s", p.rubyGemHome(d)),
+		fmt.Sprintf("GEM_PATH=%s", p.rubyGemHome(d)),
+		fmt.Sprintf("BUNDLE_GEMFILE=%s", filepath.Join(p.rubyGemHome(d), "Gemfile")),
+	}
+}
+
+// rubyBinDirs returns the gem and ruby bin directories of the application.
+func (p *Project) rubyBinDirs(d *def.App) []string {
+	if len(rubyDependencies(d)) == 0 {
+		return []string{}
+	}
+	return []string{filepath.Join(p.rubyGemHome(d), "bin"), rubyBinDir()}
+}
+
+func (p *Project) depRubyBuildGemfile(d interface{}) error {
+	switch d := d.(type) {
+	case *def.App:
+		{
+			done := output.Duration("Build Gemfile.")
+			deps := rubyDependencies(d)
+			names := make([]string, 0)
+			for name := range deps {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			out := fmt.Sprintf("source \"%s\"\n\n", rubyGemSource)
+			for _, name := range names {
+				out += fmt.Sprintf("gem \"%s\"", name)
+				if deps[name] != "*" && deps[name] != "" {
+					out += fmt.Sprintf(", \"%s\"", deps[name])
+				}
+				out += "\n"
+			}
+			if err := os.MkdirAll(p.rubyGemHome(d), 0755); err != nil {
+				return err
+			}
+			if err := ioutil.WriteFile(filepath.Join(p.rubyGemHome(d), "Gemfile"), []byte(out), 0655); err != nil {
+				return err
+			}
+			done()
+		}
+	}
+	return nil
+}
+
+// DepRubyGemInstall runs bundle install for application dependencies.
+func (p *Project) DepRubyGemInstall(d interface{}) error {
+	switch d := d.(type) {
+	case *def.App:
+		{
+			if len(rubyDependencies(d)) == 0 {
+				return nil
+			}
+			hash := depHash(rubyDependencies(d))
+			lockPath := filepath.Join(p.rubyGemHome(d), "Gemfile.lock")
+			if p.depIsCurrent(d, depEcosystemRuby, hash, lockPath) {
+				output.Info(fmt.Sprintf("Ruby dependencies for %s are up to date.", d.Name))
+				return nil
+			}
+			done := output.Duration(fmt.Sprintf("Install Ruby dependencies for %s.", d.Name))
+			if _, err := os.Stat(filepath.Join(rubyBinDir(), "ruby")); os.IsNotExist(err) {
+				if err := brewCommand("install", rubyBrewName); err != nil {
+					return err
+				}
+			}
+			if err := p.depRubyBuildGemfile(d); err != nil {
+				return err
+			}
+			// bundle install reuses Gemfile.lock for gems that haven't changed
+			bundleCmd := "install"
+			if p.UpdateDeps {
+				bundleCmd = "update"
+			}
+			done2 := output.Duration(fmt.Sprintf("Bundle %s.", bundleCmd))
+			if err := p.Command(d, "gem install bundler --no-document && bundle "+bundleCmd); err != nil {
+				return err
+			}
+			done2()
+			if err := p.depSaveHash(d, depEcosystemRuby, hash); err != nil {
+				return err
+			}
+			done()
+		}
+	}
+	return nil
+}
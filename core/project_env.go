@@ -43,8 +43,9 @@ func (p *Project) EnvPlatformVariables(d interface{}) string {
 
 // EnvPlatformRoutes returns PLATFORM_ROUTES environment variable.
 func (p *Project) EnvPlatformRoutes(d interface{}) string {
+	// host names match the ones the router serves
 	replaceDefault := func(path string) string {
-		return strings.ReplaceAll(path, "{default}", fmt.Sprintf("%s.default", p.Name))
+		return ProjectDefaultHostName(p, path)
 	}
 	routes := make(map[string]def.Route)
 	for _, route := range p.Routes {
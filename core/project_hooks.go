@@ -24,8 +24,11 @@ func (p *Project) executeHookCmd(cmdStr string, d *def.App) error {
 	return p.Command(d, cmdStr)
 }
 
-// Build executes build hooks for given app.
+// Build runs the build flavor and executes build hooks for given app.
 func (p *Project) Build(d *def.App) error {
+	if err := p.composerBuildFlavor(d); err != nil {
+		return err
+	}
 	done := output.Duration(fmt.Sprintf("Execute build hook for %s.", d.Name))
 	if err := p.executeHookCmd(d.Hooks.Build, d); err != nil {
 		return err
@@ -5,7 +5,7 @@ import (
 	"gitlab.com/contextualcode/platform_cc/v2/pkg/output"
 )
 
-// GetUpstreamPort returns port for given app upstream.
+// GetUpstreamPort returns port for given app or service upstream.
 func (p *Project) GetUpstreamPort(d interface{}) int {
 	portMap, err := LoadPortMap()
 	if err != nil {
@@ -22,6 +22,27 @@ func (p *Project) GetUpstreamPort(d interface{}) int {
 			}
 			return port
 		}
+	case def.Service:
+		{
+			// services like varnish are proxied to directly
+			serviceList, err := LoadServiceList()
+			if err != nil {
+				output.Warn(err.Error())
+				return 0
+			}
+			service, err := serviceList.MatchDef(d)
+			if err != nil {
+				output.Warn(err.Error())
+				return 0
+			}
+			service.SetDefinition(p, d)
+			port, err := service.Port()
+			if err != nil {
+				output.Warn(err.Error())
+				return 0
+			}
+			return port
+		}
 	}
 	return 0
 }
@@ -32,6 +32,7 @@ func (p *Project) getAppShellCommand(d *def.App) (ShellCommand, error) {
 	if err != nil {
 		return ShellCommand{}, err
 	}
+	brewAppService.SetDefinition(p, d)
 	brewServiceList := make([]*Service, 0)
 	brewServiceList = append(brewServiceList, brewAppService)
 	for _, service := range p.Services {
@@ -45,14 +46,26 @@ func (p *Project) getAppShellCommand(d *def.App) (ShellCommand, error) {
 		brewServiceList = append(brewServiceList, brewService)
 	}
 	// generate pathes
-	envPaths := []string{
+	envPaths := make([]string, 0)
+	nodeVer := ""
+	if nodeUsed(d) {
+		nodeVer = nodeVersion(d)
+		if nodePath, err := nodeBinDir(nodeVer); err == nil {
+			envPaths = append(envPaths, nodePath)
+		} else {
+			output.Warn(err.Error())
+		}
+	}
+	envPaths = append(envPaths,
+		composerBinDir(composerMajorVersion(d)),
 		filepath.Join(p.Path, ".global", "bin"),
 		filepath.Join(p.Path, ".global", "vendor", "bin"),
 		filepath.Join(p.Path, ".global", "node_modules", "bin"),
 		filepath.Join(p.Path, ".platformsh", "bin"),
-		filepath.Join(GetDir(HomeDir), ".pyenv", "versions", "3.10.0", "bin"),
-		filepath.Join(GetDir(HomeDir), ".pyenv", "versions", "2.7.18", "bin"),
-	}
+	)
+	envPaths = append(envPaths, p.pythonBinDirs(d)...)
+	envPaths = append(envPaths, p.rubyBinDirs(d)...)
+	envPaths = append(envPaths, p.golangBinDirs(d)...)
 	// inject env vars
 	env := make([]string, 0)
 	env = append(env, ServicesEnv(brewServiceList)...)
@@ -60,6 +73,21 @@ func (p *Project) getAppShellCommand(d *def.App) (ShellCommand, error) {
 	//env = append(env, "HOME="+p.Path)
 	//env = append(env, fmt.Sprintf("NVM_DIR=%s/.nvm", GetDir(HomeDir)))
 	env = append(env, fmt.Sprintf("TERM=%s", os.Getenv("TERM")))
+	if nodeVer != "" {
+		env = append(env, fmt.Sprintf("PBREW_NODE_VERSION=%s", nodeVer))
+	}
+	if len(nodePackageManagerVersions(d)) > 0 {
+		env = append(env, fmt.Sprintf("COREPACK_HOME=%s", p.nodeCorepackHome(d)))
+	}
+	env = append(env, p.rubyEnv(d)...)
+	env = append(env, p.golangEnv(d)...)
+	if brewAppService.UpstreamSocketFamily() == "tcp" {
+		if port, err := brewAppService.Port(); err == nil {
+			env = append(env, fmt.Sprintf("PORT=%d", port))
+		}
+	} else {
+		env = append(env, fmt.Sprintf("SOCKET=%s", brewAppService.UpstreamSocketPath()))
+	}
 
 	for k, v := range p.Env(d) {
 		env = append(env, fmt.Sprintf("%s=%s", k, v))
@@ -128,7 +156,12 @@ func (p *Project) Command(d *def.App, cmdStr string) error {
 	if err != nil {
 		return err
 	}
-	cmdStr = "source $(brew --prefix nvm)/nvm.sh && " + cmdStr
+	// apps without a node version use nvm's default
+	if nodeUsed(d) {
+		cmdStr = "source $(brew --prefix nvm)/nvm.sh --no-use && " + cmdStr
+	} else {
+		cmdStr = "source $(brew --prefix nvm)/nvm.sh && " + cmdStr
+	}
 	cmd.Args = []string{"-c", cmdStr}
 	if err := cmd.Interactive(); err != nil {
 		return err
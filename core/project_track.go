@@ -4,7 +4,9 @@ import (
 	"encoding/json"
 	"io/ioutil"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/pkg/errors"
@@ -13,6 +15,10 @@ import (
 // ProjectTileFile is the name of the project tracking file.
 const ProjectTrackFile = "projects.json"
 
+// ProjectDomainFile is the name of the file default domains synced from Platform.sh are stored in, they're kept
+// when the project is stopped unlike the tracking of running projects.
+const ProjectDomainFile = "project_domains.json"
+
 // ProjectTrack tracks running project.
 type ProjectTrack struct {
 	Name     string    `json:"name"`
@@ -126,3 +132,67 @@ func ProjectTrackRemove(p *Project) error {
 	}
 	return nil
 }
+
+func loadProjectDomains() (map[string]string, error) {
+	out := make(map[string]string)
+	rawData, err := ioutil.ReadFile(filepath.Join(GetDir(UserDir), ProjectDomainFile))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return out, nil
+		}
+		return out, errors.WithStack(err)
+	}
+	if err := json.Unmarshal(rawData, &out); err != nil {
+		return out, errors.WithStack(err)
+	}
+	return out, nil
+}
+
+// ProjectDomainGet returns the stored default domain of the project at given path.
+func ProjectDomainGet(projPath string) (string, error) {
+	domains, err := loadProjectDomains()
+	if err != nil {
+		return "", err
+	}
+	return domains[projPath], nil
+}
+
+// ProjectDomainSet stores the default domain of given project, an empty domain removes it.
+func ProjectDomainSet(p *Project, domain string) error {
+	domains, err := loadProjectDomains()
+	if err != nil {
+		return err
+	}
+	if domain == "" {
+		delete(domains, p.Path)
+	} else {
+		domains[p.Path] = domain
+	}
+	rawData, err := json.Marshal(domains)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(GetDir(UserDir), ProjectDomainFile), rawData, mkdirPerm); err != nil {
+		return errors.WithStack(err)
+	}
+	p.DefaultDomain = domain
+	return nil
+}
+
+// ProjectDomainSync fetches the default domain of given project with the Platform.sh CLI and stores it.
+func ProjectDomainSync(p *Project) error {
+	cmd := exec.Command("bash", "-c", "platform project:info -- default_domain")
+	cmd.Dir = p.Path
+	cmd.Stdin = os.Stdin
+	// notices and prompts of the CLI are written to stderr, only stdout has the domain
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return errors.WithStack(errors.WithMessage(ErrDefaultDomainNotFound, err.Error()))
+	}
+	domain := strings.TrimSpace(string(out))
+	if domain == "" || strings.ContainsAny(domain, " \n/") {
+		return errors.WithStack(errors.WithMessage(ErrDefaultDomainNotFound, domain))
+	}
+	return ProjectDomainSet(p, domain)
+}
@@ -0,0 +1,159 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/def"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/output"
+)
+
+const python3DefaultVersion = "3"
+const python2DefaultVersion = "2.7"
+
+// pythonVersionDependency is the dependencies.python2/python3 entry that selects the Python version instead of a package.
+const pythonVersionDependency = "python"
+
+var pythonVersionConstraintRegex = regexp.MustCompile(`[0-9]+(\.[0-9]+)*`)
+
+// pythonPrefixes caches resolved pyenv install paths by requested version.
+var pythonPrefixes = map[string]string{}
+
+// pythonDependencies returns the dependencies.python2/python3 entries by major version.
+func pythonDependencies(d *def.App) map[int]map[string]string {
+	return map[int]map[string]string{2: d.Dependencies.Python2, 3: d.Dependencies.Python3}
+}
+
+// pythonVersions returns the Python versions needed by the application by major version.
+// The version comes from a python:<ver> type or a python entry in dependencies.python2/python3.
+func pythonVersions(d *def.App) map[int]string {
+	out := make(map[int]string)
+	if d.GetTypeName() == "python" {
+		typeSplit := strings.SplitN(d.Type, ":", 2)
+		if len(typeSplit) == 2 && strings.HasPrefix(typeSplit[1], "2") {
+			out[2] = typeSplit[1]
+		} else if len(typeSplit) == 2 && typeSplit[1] != "" {
+			out[3] = typeSplit[1]
+		} else {
+			out[3] = python3DefaultVersion
+		}
+	}
+	for major, deps := range pythonDependencies(d) {
+		if len(deps) == 0 || out[major] != "" {
+			continue
+		}
+		if version := pythonVersionConstraintRegex.FindString(deps[pythonVersionDependency]); version != "" {
+			out[major] = version
+			continue
+		}
+		out[major] = python2DefaultVersion
+		if major == 3 {
+			out[major] = python3DefaultVersion
+		}
+	}
+	return out
+}
+
+// pythonMajorVersions returns the keys of given version map, newest first.
+func pythonMajorVersions(versions map[int]string) []int {
+	out := make([]int, 0)
+	for major := range versions {
+		out = append(out, major)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(out)))
+	return out
+}
+
+// pyenvCommand runs pyenv with given arguments and returns its output.
+func pyenvCommand(args ...string) ([]byte, error) {
+	cmd := exec.Command(filepath.Join(GetDir(BrewDir), "bin", "pyenv"), args...)
+	cmd.Env = brewEnv()
+	return cmd.Output()
+}
+
+// pythonPrefix returns the pyenv install path of the latest release of given Python version, installing it when needed.
+func pythonPrefix(version string) (string, error) {
+	if prefix, ok := pythonPrefixes[version]; ok {
+		return prefix, nil
+	}
+	out, err := pyenvCommand("latest", version)
+	if err != nil {
+		if out, err = pyenvCommand("latest", "--known", version); err != nil {
+			return "", errors.WithStack(errors.WithMessage(ErrPythonVersionNotFound, version))
+		}
+		fullVersion := strings.TrimSpace(string(out))
+		done := output.Duration(fmt.Sprintf("Install Python %s.", fullVersion))
+		cmd := exec.Command(filepath.Join(GetDir(BrewDir), "bin", "pyenv"), "install", "--skip-existing", fullVersion)
+		cmd.Env = brewEnv()
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			return "", errors.WithStack(err)
+		}
+		done()
+	}
+	prefix := filepath.Join(GetDir(HomeDir), ".pyenv", "versions", strings.TrimSpace(string(out)))
+	pythonPrefixes[version] = prefix
+	return prefix, nil
+}
+
+// pythonVenvPath returns the path to the application's virtualenv for given Python major version.
+func (p *Project) pythonVenvPath(d *def.App, major int) string {
+	return filepath.Join(p.DepInstallPath(d), fmt.Sprintf("python%d", major))
+}
+
+// pythonVenvCreate creates the application's virtualenv for given Python major version and upgrades pip and setuptools in it.
+func (p *Project) pythonVenvCreate(d *def.App, major int, version string) error {
+	venvPath := p.pythonVenvPath(d, major)
+	venvPythonPath := filepath.Join(venvPath, "bin", "python")
+	if _, err := os.Stat(venvPythonPath); os.IsNotExist(err) {
+		prefix, err := pythonPrefix(version)
+		if err != nil {
+			return err
+		}
+		pythonPath := filepath.Join(prefix, "bin", "python")
+		cmdStr := fmt.Sprintf("%s -m venv %s", pythonPath, venvPath)
+		if major == 2 {
+			cmdStr = fmt.Sprintf(
+				"%s -m pip install --upgrade virtualenv && %s -m virtualenv %s",
+				pythonPath, pythonPath, venvPath,
+			)
+		}
+		done := output.Duration(fmt.Sprintf("Create Python %d virtualenv.", major))
+		if err := p.Command(d, cmdStr); err != nil {
+			return err
+		}
+		done()
+	}
+	return p.Command(d, fmt.Sprintf("%s -m pip install --upgrade pip setuptools wheel", venvPythonPath))
+}
+
+// pythonBinDirs returns the virtualenv and interpreter bin directories of the application.
+// Python is only resolved for Python applications and applications with Python dependencies,
+// other applications get the pyenv shims so the pyenv global version is used.
+func (p *Project) pythonBinDirs(d *def.App) []string {
+	out := make([]string, 0)
+	versions := pythonVersions(d)
+	if len(versions) == 0 {
+		return append(out, filepath.Join(GetDir(HomeDir), ".pyenv", "shims"))
+	}
+	for _, major := range pythonMajorVersions(versions) {
+		venvBinPath := filepath.Join(p.pythonVenvPath(d, major), "bin")
+		if _, err := os.Stat(venvBinPath); err == nil {
+			out = append(out, venvBinPath)
+		}
+		prefix, err := pythonPrefix(versions[major])
+		if err != nil {
+			output.Warn(err.Error())
+			continue
+		}
+		out = append(out, filepath.Join(prefix, "bin"))
+	}
+	return out
+}
@@ -27,10 +27,14 @@ type Service struct {
 	StartCmd        string            `yaml:"start"`
 	StopCmd         string            `yaml:"stop"`
 	ReloadCmd       string            `yaml:"reload"`
+	AppStartCmd     string            `yaml:"app_start"`
+	AppStopCmd      string            `yaml:"app_stop"`
+	AppReloadCmd    string            `yaml:"app_reload"`
 	InstallCheckCmd string            `yaml:"install_check"`
 	Dependencies    []string          `yaml:"dependencies"`
 	Multiple        bool              `yaml:"multiple"`
 	PortOverride    int               `yaml:"port"`
+	Version         string            `yaml:"version"`
 	ProjectName     string
 	usePbrewBottles bool
 	project         *Project
@@ -98,6 +102,12 @@ func (s *Service) InstallCheck() bool {
 
 // PreInstall runs the pre install command for the service.
 func (s *Service) PreInstall() error {
+	// fetch solr release archives
+	if s.IsSolr() {
+		if err := s.solrFetchArchives(); err != nil {
+			return err
+		}
+	}
 	// run cmd
 	if s.PreInstallCmd != "" {
 		cmdStr := s.injectCommandParams(s.PreInstallCmd)
@@ -206,7 +216,7 @@ func (s *Service) Start() error {
 	}
 	// execute start cmd
 	done2 := output.Duration("Start up.")
-	cmdStr := s.injectCommandParams(s.StartCmd)
+	cmdStr := s.injectCommandParams(s.startCmd())
 	cmd := NewShellCommand()
 	cmd.Env = ServicesEnv([]*Service{s})
 	if s.project != nil && s.definition != nil {
@@ -242,7 +252,7 @@ func (s *Service) Stop() error {
 		return errors.WithStack(errors.WithMessage(ErrServiceNotRunning, s.DisplayName()))
 	}
 	// execute stop cmd
-	cmdStr := s.injectCommandParams(s.StopCmd)
+	cmdStr := s.injectCommandParams(s.stopCmd())
 	cmd := NewShellCommand()
 	cmd.Args = []string{"-c", cmdStr}
 	cmd.Env = ServicesEnv([]*Service{s})
@@ -257,7 +267,7 @@ func (s *Service) Stop() error {
 func (s *Service) Reload() error {
 	done := output.Duration(fmt.Sprintf("Reload %s.", s.DisplayName()))
 	// check status
-	if s.ReloadCmd == "" {
+	if s.reloadCmd() == "" {
 		return errors.WithStack(errors.WithMessage(ErrServiceReloadNotDefined, s.DisplayName()))
 	}
 	if !s.IsInstalled() {
@@ -267,7 +277,7 @@ func (s *Service) Reload() error {
 		return errors.WithStack(errors.WithMessage(ErrServiceNotRunning, s.DisplayName()))
 	}
 	// execute reload cmd
-	cmdStr := s.injectCommandParams(s.ReloadCmd)
+	cmdStr := s.injectCommandParams(s.reloadCmd())
 	cmd := NewShellCommand()
 	cmd.Args = []string{"-c", cmdStr}
 	cmd.Env = ServicesEnv([]*Service{s})
@@ -318,6 +328,10 @@ func (s *Service) PostStart() error {
 				if err := s.solrPostSetup(); err != nil {
 					return err
 				}
+			} else if s.IsMongoDB() {
+				if err := s.mongoDBPostSetup(); err != nil {
+					return err
+				}
 			}
 			done()
 			break
@@ -354,6 +368,10 @@ func (s *Service) Purge() error {
 				if err := s.mySQLPurge(); err != nil {
 					return err
 				}
+			} else if s.IsMongoDB() {
+				if err := s.mongoDBPurge(); err != nil {
+					return err
+				}
 			}
 			done()
 			break
@@ -376,10 +394,7 @@ func (s *Service) Port() (int, error) {
 
 // SocketPath returns path to service socket.
 func (s *Service) SocketPath() string {
-	if s.Multiple && s.project != nil {
-		return filepath.Join(GetDir(RunDir), fmt.Sprintf("%s-%s.sock", strings.ReplaceAll(s.BrewAppName(), "@", "-"), s.project.Name))
-	}
-	return filepath.Join(GetDir(RunDir), fmt.Sprintf("%s.sock", strings.ReplaceAll(s.BrewAppName(), "@", "-")))
+	return filepath.Join(GetDir(RunDir), fmt.Sprintf("%s%s.sock", strings.ReplaceAll(s.BrewAppName(), "@", "-"), s.instanceSuffix()))
 }
 
 // UpstreamSocketPath returns path to app upstream socket.
@@ -387,11 +402,83 @@ func (s *Service) UpstreamSocketPath() string {
 	return s.SocketPath()
 }
 
+// UpstreamSocketFamily returns the socket family the app upstream listens on, from web.upstream.socket_family.
+// PHP-FPM listens on a unix socket by default, other runtimes on a tcp port.
+func (s *Service) UpstreamSocketFamily() string {
+	if d, ok := s.definition.(*def.App); ok && d.Web.Upstream.SocketFamily != "" {
+		return d.Web.Upstream.SocketFamily
+	}
+	if !s.IsPHP() || s.runsAppStart() {
+		return "tcp"
+	}
+	return "unix"
+}
+
+// UpstreamProtocol returns the protocol the app upstream speaks, from web.upstream.protocol.
+// PHP-FPM only speaks fastcgi, http is only used for PHP applications that serve it with web.commands.start.
+func (s *Service) UpstreamProtocol() string {
+	if s.IsPHP() && !s.runsAppStart() {
+		return "fastcgi"
+	}
+	if d, ok := s.definition.(*def.App); ok && d.Web.Upstream.Protocol != "" {
+		return d.Web.Upstream.Protocol
+	}
+	return "http"
+}
+
+// UpstreamAddress returns the address the app upstream listens on.
+func (s *Service) UpstreamAddress() string {
+	if s.UpstreamSocketFamily() == "tcp" {
+		port, err := s.Port()
+		if err != nil {
+			output.Warn(err.Error())
+		}
+		return fmt.Sprintf("127.0.0.1:%d", port)
+	}
+	return s.UpstreamSocketPath()
+}
+
 // BrewAppName returns the brew app name without namespace.
 func (s *Service) BrewAppName() string {
 	return brewAppName(s.BrewName)
 }
 
+// ActualVersion returns the version of the Homebrew service.
+func (s *Service) ActualVersion() string {
+	if s.Version != "" {
+		return s.Version
+	}
+	nameSplit := strings.Split(s.BrewAppName(), "@")
+	if len(nameSplit) < 2 {
+		return ""
+	}
+	return strings.TrimSpace(nameSplit[1])
+}
+
+// RequestedVersion returns the version requested by the service definition.
+func (s *Service) RequestedVersion() string {
+	typeName := ""
+	switch d := s.definition.(type) {
+	case *def.App:
+		{
+			typeName = d.Type
+			break
+		}
+	case *def.Service:
+		{
+			typeName = d.Type
+			break
+		}
+	case def.Service:
+		{
+			typeName = d.Type
+			break
+		}
+	}
+	_, version := splitServiceType(typeName)
+	return version
+}
+
 // DisplayName returns the name the service should be displayed to the user as.
 func (s *Service) DisplayName() string {
 	if s.Name != "" {
@@ -402,18 +489,34 @@ func (s *Service) DisplayName() string {
 
 // PidPath returns path to service pid file.
 func (s *Service) PidPath() string {
-	if s.Multiple && s.project != nil {
-		return filepath.Join(GetDir(RunDir), fmt.Sprintf("%s-%s.pid", strings.ReplaceAll(s.BrewAppName(), "@", "-"), s.project.Name))
-	}
-	return filepath.Join(GetDir(RunDir), fmt.Sprintf("%s.pid", strings.ReplaceAll(s.BrewAppName(), "@", "-")))
+	return filepath.Join(GetDir(RunDir), fmt.Sprintf("%s%s.pid", strings.ReplaceAll(s.BrewAppName(), "@", "-"), s.instanceSuffix()))
 }
 
 // ConfigPath returns path to service config file.
 func (s *Service) ConfigPath() string {
-	if s.Multiple && s.project != nil {
-		return filepath.Join(GetDir(ConfDir), fmt.Sprintf("%s-%s.conf", strings.ReplaceAll(s.BrewAppName(), "@", "-"), s.project.Name))
+	return filepath.Join(GetDir(ConfDir), fmt.Sprintf("%s%s.conf", strings.ReplaceAll(s.BrewAppName(), "@", "-"), s.instanceSuffix()))
+}
+
+// appInstanceName returns the application name when the service runs an application, like PHP-FPM or an
+// application's web.commands.start, so every application gets its own process, config, pid file and port.
+// It's empty for other services.
+func (s *Service) appInstanceName() string {
+	if d, ok := s.definition.(*def.App); ok {
+		return d.Name
 	}
-	return filepath.Join(GetDir(ConfDir), fmt.Sprintf("%s.conf", strings.ReplaceAll(s.BrewAppName(), "@", "-")))
+	return ""
+}
+
+// instanceSuffix returns the suffix that keeps the files of multi-instance services apart per project,
+// and per application for services that run an application.
+func (s *Service) instanceSuffix() string {
+	if !s.Multiple || s.project == nil {
+		return ""
+	}
+	if appName := s.appInstanceName(); appName != "" {
+		return fmt.Sprintf("-%s-%s", s.project.Name, appName)
+	}
+	return fmt.Sprintf("-%s", s.project.Name)
 }
 
 // DataPath returns path to service data directory.
@@ -429,10 +532,20 @@ func (s *Service) DataPath() string {
 func (s *Service) ConfigParams() map[string]interface{} {
 	if s.IsPHP() {
 		return s.phpConfigParams()
+	} else if s.IsVarnish() {
+		return s.varnishConfigParams()
+	} else if s.IsMongoDB() {
+		return s.mongoDBConfigParams()
 	}
 	return map[string]interface{}{}
 }
 
+// copy returns a copy of the service so that its definition can be set without affecting other matches.
+func (s *Service) copy() *Service {
+	c := *s
+	return &c
+}
+
 // SetDefinition set the project and service definition for this service.
 func (s *Service) SetDefinition(p *Project, d interface{}) {
 	s.project = p
@@ -457,5 +570,9 @@ func (s *Service) injectCommandParams(cmd string) string {
 	cmd = strings.ReplaceAll(cmd, "{DATA_PATH}", s.DataPath())
 	cmd = strings.ReplaceAll(cmd, "{LOG_PATH}", GetDir(LogDir))
 	cmd = strings.ReplaceAll(cmd, "{HOME_PATH}", GetDir(HomeDir))
+	cmd = strings.ReplaceAll(cmd, "{BOTTLE_PATH}", GetDir(BottleDir))
+	cmd = strings.ReplaceAll(cmd, "{PHP_INI}", s.phpIniPath())
+	cmd = s.solrInjectArchiveParams(cmd)
+	cmd = s.appInjectCommandParams(cmd)
 	return cmd
 }
@@ -1,6 +1,7 @@
 package core
 
 import (
+	"os"
 	"path/filepath"
 	"strings"
 )
@@ -11,7 +12,7 @@ func ServicesEnv(services []*Service) []string {
 	for _, service := range services {
 		envPath = append(envPath, filepath.Join(GetDir(BrewDir), "opt", service.BrewAppName(), "bin"))
 		for _, dependency := range service.Dependencies {
-			envPath = append(envPath, filepath.Join(GetDir(BrewDir), "opt", dependency, "bin"))
+			envPath = append(envPath, filepath.Join(GetDir(BrewDir), "opt", brewAppName(dependency), "bin"))
 		}
 	}
 	envPath = append(envPath, filepath.Join(GetDir(BrewDir), "bin"))
@@ -29,7 +30,10 @@ func ServicesEnv(services []*Service) []string {
 	env = append(env, "HOME="+GetDir(HomeDir))
 	for _, service := range services {
 		if service.IsPHP() {
-			env = append(env, "PHPRC="+service.DataPath())
+			env = append(env, "PHPRC="+service.phpIniPath())
+			if _, err := os.Stat(service.phpScanDirPath()); err == nil {
+				env = append(env, "PHP_INI_SCAN_DIR="+service.phpScanDirPath())
+			}
 		} else if service.IsSolr() {
 			envPath = append(envPath, filepath.Join(GetDir(BrewDir), "opt", "solr", "bin"))
 		}
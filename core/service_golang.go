@@ -0,0 +1,91 @@
+package core
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/def"
+)
+
+// golangPath returns the GOPATH for given application.
+func (p *Project) golangPath(d *def.App) string {
+	return filepath.Join(p.DepInstallPath(d), "go")
+}
+
+// golangEnv returns the environment variables that keep go's module and build caches in the application's .global dir.
+func (p *Project) golangEnv(d *def.App) []string {
+	if d.GetTypeName() != "golang" {
+		return []string{}
+	}
+	return []string{
+		fmt.Sprintf("GOPATH=%s", p.golangPath(d)),
+		fmt.Sprintf("GOCACHE=%s", filepath.Join(p.DepInstallPath(d), "go-build")),
+	}
+}
+
+// golangBinDirs returns the GOPATH bin directory of given application.
+func (p *Project) golangBinDirs(d *def.App) []string {
+	if d.GetTypeName() != "golang" {
+		return []string{}
+	}
+	return []string{filepath.Join(p.golangPath(d), "bin")}
+}
+
+// appServesHTTP returns true if given application serves http itself with web.commands.start.
+func appServesHTTP(d *def.App) bool {
+	return d.Web.Commands.Start != "" && d.Web.Upstream.Protocol == "http"
+}
+
+// runsAppStart returns true if the service runs the application's web.commands.start with its app_start command
+// instead of its own process, like PHP applications that serve http themselves.
+func (s *Service) runsAppStart() bool {
+	d, ok := s.definition.(*def.App)
+	return ok && s.AppStartCmd != "" && appServesHTTP(d)
+}
+
+// startCmd returns the command that starts the service.
+func (s *Service) startCmd() string {
+	if s.runsAppStart() {
+		return s.AppStartCmd
+	}
+	return s.StartCmd
+}
+
+// stopCmd returns the command that stops the service.
+func (s *Service) stopCmd() string {
+	if s.runsAppStart() {
+		return s.AppStopCmd
+	}
+	return s.StopCmd
+}
+
+// reloadCmd returns the command that reloads the service.
+func (s *Service) reloadCmd() string {
+	if s.runsAppStart() {
+		return s.AppReloadCmd
+	}
+	return s.ReloadCmd
+}
+
+// appLogPath returns the path to the log of an application's web.commands.start process.
+func (s *Service) appLogPath() string {
+	if d, ok := s.definition.(*def.App); ok && s.project != nil {
+		return filepath.Join(GetDir(LogDir), fmt.Sprintf("app_%s_%s.log", s.project.Name, d.Name))
+	}
+	return filepath.Join(GetDir(LogDir), fmt.Sprintf("%s.log", strings.ReplaceAll(s.BrewAppName(), "@", "-")))
+}
+
+// appInjectCommandParams injects the application's root, web.commands.start and log path in to given command.
+func (s *Service) appInjectCommandParams(cmd string) string {
+	appRoot := ""
+	startCmd := ""
+	if d, ok := s.definition.(*def.App); ok {
+		appRoot = d.Path
+		startCmd = d.Web.Commands.Start
+	}
+	cmd = strings.ReplaceAll(cmd, "{APP_ROOT}", appRoot)
+	cmd = strings.ReplaceAll(cmd, "{APP_START}", "'"+strings.ReplaceAll(startCmd, "'", `'\''`)+"'")
+	cmd = strings.ReplaceAll(cmd, "{APP_LOG}", s.appLogPath())
+	return cmd
+}
@@ -1,6 +1,9 @@
 package core
 
 import (
+	"fmt"
+	"regexp"
+	"strconv"
 	"strings"
 
 	"gitlab.com/contextualcode/platform_cc/v2/pkg/output"
@@ -29,24 +32,112 @@ func LoadServiceList() (ServiceList, error) {
 	return loadedServiceList, nil
 }
 
+var warnedServiceVersions = make(map[string]bool)
+
+var serviceTypeVersionRegex = regexp.MustCompile(`^(.+)-[0-9*][0-9.*]*$`)
+
 // Match matches platform.sh service with homebrew service.
 func (s ServiceList) Match(name string) (*Service, error) {
 	matchName := strings.ReplaceAll(name, ":", "-")
 	for serviceName, service := range s {
 		serviceName = strings.ReplaceAll(serviceName, ":", "-")
 		if serviceName == matchName {
-			return service, nil
+			return service.copy(), nil
 		}
 	}
 	for serviceName, service := range s {
 		serviceName = strings.ReplaceAll(serviceName, ":", "-")
 		if wildcardCompare(matchName, serviceName) {
-			return service, nil
+			return service.copy(), nil
 		}
 	}
+	service := s.matchNearestVersion(name)
+	if service != nil {
+		return service.copy(), nil
+	}
 	return nil, errors.WithStack(errors.WithMessage(ErrServiceNotFound, name))
 }
 
+// matchNearestVersion finds the service of the same type with the version nearest to the requested one.
+func (s ServiceList) matchNearestVersion(name string) *Service {
+	typeName, version := splitServiceType(name)
+	if version == "" {
+		return nil
+	}
+	var match *Service
+	for serviceName, service := range s {
+		serviceName = strings.ReplaceAll(serviceName, ":", "-")
+		m := serviceTypeVersionRegex.FindStringSubmatch(serviceName)
+		if m == nil || m[1] != typeName || service.ActualVersion() == "" {
+			continue
+		}
+		if match == nil || isNearerVersion(version, service.ActualVersion(), match.ActualVersion()) {
+			match = service
+		}
+	}
+	if match != nil && !warnedServiceVersions[name] {
+		warnedServiceVersions[name] = true
+		output.Warn(fmt.Sprintf(
+			"Service '%s' version %s is not available, using version %s instead.",
+			typeName, version, match.ActualVersion(),
+		))
+	}
+	return match
+}
+
+// splitServiceType splits a platform.sh service type in to its name and version.
+func splitServiceType(name string) (string, string) {
+	typeSplit := strings.SplitN(name, ":", 2)
+	if len(typeSplit) < 2 {
+		return typeSplit[0], ""
+	}
+	return typeSplit[0], strings.TrimSpace(typeSplit[1])
+}
+
+// compareVersions compares two dot separated version numbers.
+func compareVersions(a string, b string) int {
+	aSplit := strings.Split(a, ".")
+	bSplit := strings.Split(b, ".")
+	for i := 0; i < len(aSplit) || i < len(bSplit); i++ {
+		av, bv := 0, 0
+		if i < len(aSplit) {
+			av, _ = strconv.Atoi(aSplit[i])
+		}
+		if i < len(bSplit) {
+			bv, _ = strconv.Atoi(bSplit[i])
+		}
+		if av != bv {
+			if av < bv {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// isNearerVersion returns true if candidate is a better match for requested than current.
+// Versions with the same major version are preferred, then the closest newer version, then the closest older version.
+func isNearerVersion(requested string, candidate string, current string) bool {
+	major := func(v string) string {
+		return strings.Split(v, ".")[0]
+	}
+	candidateSameMajor := major(candidate) == major(requested)
+	currentSameMajor := major(current) == major(requested)
+	if candidateSameMajor != currentSameMajor {
+		return candidateSameMajor
+	}
+	candidateNewer := compareVersions(candidate, requested) >= 0
+	currentNewer := compareVersions(current, requested) >= 0
+	if candidateNewer != currentNewer {
+		return candidateNewer
+	}
+	if candidateNewer {
+		return compareVersions(candidate, current) < 0
+	}
+	return compareVersions(candidate, current) > 0
+}
+
 // MatchDef matches definition with its homebrew service.
 func (s ServiceList) MatchDef(d interface{}) (*Service, error) {
 	switch d := d.(type) {
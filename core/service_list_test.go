@@ -0,0 +1,48 @@
+package core
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestServiceListMatchNearestVersion(t *testing.T) {
+	appPath, err := filepath.Abs("..")
+	if err != nil {
+		t.Fatal(err)
+	}
+	prevAppDir := appDirectories[AppDir]
+	appDirectories[AppDir] = appPath
+	loadedServiceList = nil
+	t.Cleanup(func() {
+		appDirectories[AppDir] = prevAppDir
+		loadedServiceList = nil
+	})
+	serviceList, err := LoadServiceList()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		brewName string
+		warned   bool
+	}{
+		{name: "redis:7.2", brewName: "redis"},
+		{name: "redis:6.2", brewName: "redis@6.2"},
+		{name: "redis:5.0", brewName: "redis@6.2", warned: true},
+		{name: "redis-persistent:5.0", brewName: "redis@6.2", warned: true},
+	}
+	for _, tt := range tests {
+		delete(warnedServiceVersions, tt.name)
+		service, err := serviceList.Match(tt.name)
+		if err != nil {
+			t.Errorf("expected %s to match, got %s", tt.name, err)
+			continue
+		}
+		if service.BrewName != tt.brewName {
+			t.Errorf("expected %s for %s, got %s", tt.brewName, tt.name, service.BrewName)
+		}
+		if warnedServiceVersions[tt.name] != tt.warned {
+			t.Errorf("expected warning %v for %s, got %v", tt.warned, tt.name, warnedServiceVersions[tt.name])
+		}
+	}
+}
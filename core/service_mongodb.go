@@ -0,0 +1,312 @@
+package core
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/def"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/output"
+)
+
+const mongoDBDefaultDatabase = "main"
+
+// mongoDBAdminUser is the user pbrew manages databases and project users with.
+const mongoDBAdminUser = "pbrew_admin"
+
+// mongoDBAdminPasswordFile is the file in the data directory that stores the generated admin password.
+// Authorization is enabled once it exists.
+const mongoDBAdminPasswordFile = "pbrew_admin.secret"
+
+// mongoDBRestartTimeout is how long to wait for mongod to stop or accept connections.
+const mongoDBRestartTimeout = 30 * time.Second
+
+// IsMongoDB returns true if service is mongodb.
+func (s *Service) IsMongoDB() bool {
+	return strings.HasPrefix(s.BrewAppName(), "mongodb")
+}
+
+// MongoDBGetDatabases returns list of databases for the service definition.
+func (s *Service) MongoDBGetDatabases() []string {
+	var d def.Service
+	switch sd := s.definition.(type) {
+	case *def.Service:
+		{
+			if sd == nil {
+				return []string{}
+			}
+			d = *sd
+			break
+		}
+	case def.Service:
+		{
+			d = sd
+			break
+		}
+	default:
+		{
+			return []string{}
+		}
+	}
+	if !s.IsMongoDB() {
+		return []string{}
+	}
+	if d.Configuration["databases"] == nil {
+		return []string{mongoDBDefaultDatabase}
+	}
+	out := make([]string, 0)
+	databases, _ := d.Configuration["databases"].([]interface{})
+	for _, database := range databases {
+		if database, ok := database.(string); ok && database != "" {
+			out = append(out, database)
+		}
+	}
+	if len(out) == 0 {
+		return []string{mongoDBDefaultDatabase}
+	}
+	return out
+}
+
+func (s *Service) mongoDBDefaultDatabase() string {
+	database := s.MongoDBGetDatabases()
+	if len(database) == 0 || s.project == nil {
+		return ""
+	}
+	return s.project.ResolveDatabase(database[0])
+}
+
+// MongoDBUser returns the mongodb user name for the current project.
+func (s *Service) MongoDBUser() string {
+	if s.project == nil {
+		return mysqlUser
+	}
+	return fmt.Sprintf("%s_%s", mysqlUser, strings.ReplaceAll(s.project.Name, "-", "_"))
+}
+
+// MongoDBPassword returns the generated mongodb password for the current project.
+func (s *Service) MongoDBPassword() string {
+	if s.project == nil {
+		return mysqlPass
+	}
+	hash := sha256.Sum256([]byte(s.project.Name + ":" + s.project.Path))
+	return hex.EncodeToString(hash[:])[0:16]
+}
+
+// mongoDBAdminPassword returns the stored admin password, empty when the admin user hasn't been created yet.
+func (s *Service) mongoDBAdminPassword() string {
+	raw, err := ioutil.ReadFile(filepath.Join(s.DataPath(), mongoDBAdminPasswordFile))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(raw))
+}
+
+// mongoDBAuthArgs returns the arguments that authenticate mongosh and the database tools as the admin user.
+func (s *Service) mongoDBAuthArgs() []string {
+	password := s.mongoDBAdminPassword()
+	if password == "" {
+		return []string{}
+	}
+	return []string{"--username", mongoDBAdminUser, "--password", password, "--authenticationDatabase", "admin"}
+}
+
+func (s *Service) mongoDBConfigParams() map[string]interface{} {
+	return map[string]interface{}{
+		"Authorization": s.mongoDBAdminPassword() != "",
+	}
+}
+
+// mongoDBWaitRunning waits until mongod is running or stopped.
+func (s *Service) mongoDBWaitRunning(running bool) error {
+	for start := time.Now(); time.Since(start) < mongoDBRestartTimeout; time.Sleep(time.Second) {
+		if s.IsRunning() == running {
+			return nil
+		}
+	}
+	if running {
+		return errors.WithStack(errors.WithMessage(ErrServiceNotRunning, s.DisplayName()))
+	}
+	return errors.WithStack(errors.WithMessage(ErrServiceAlreadyRunning, s.DisplayName()))
+}
+
+// mongoDBBootstrapAdmin creates the admin user and restarts mongod with authorization enabled.
+// mongod runs without authorization until then, so this also works for data created before authorization was used.
+func (s *Service) mongoDBBootstrapAdmin() error {
+	if s.mongoDBAdminPassword() != "" {
+		return nil
+	}
+	done := output.Duration("Enable MongoDB authorization.")
+	secret := make([]byte, 16)
+	if _, err := rand.Read(secret); err != nil {
+		return errors.WithStack(err)
+	}
+	password := hex.EncodeToString(secret)
+	output.Info(fmt.Sprintf("Create %s user.", mongoDBAdminUser))
+	if err := s.MongoDBExecute("admin", fmt.Sprintf(
+		"if (db.getUser('%[1]s')) { db.updateUser('%[1]s', {pwd: '%[2]s', roles: ['root']}) } else { db.createUser({user: '%[1]s', pwd: '%[2]s', roles: ['root']}) }",
+		mongoDBAdminUser,
+		password,
+	)); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(filepath.Join(s.DataPath(), mongoDBAdminPasswordFile), []byte(password), 0600); err != nil {
+		return errors.WithStack(err)
+	}
+	// restart with authorization enabled
+	if err := s.GenerateConfigFile(); err != nil {
+		return err
+	}
+	if err := s.Stop(); err != nil {
+		return err
+	}
+	if err := s.mongoDBWaitRunning(false); err != nil {
+		return err
+	}
+	if err := s.Start(); err != nil {
+		return err
+	}
+	if err := s.mongoDBWaitRunning(true); err != nil {
+		return err
+	}
+	// give mongod time to accept connections
+	time.Sleep(time.Second * 3)
+	done()
+	return nil
+}
+
+// MongoDBShell enters the mongodb shell.
+func (s *Service) MongoDBShell(database string) error {
+	if !s.IsMongoDB() {
+		return errors.WithStack(errors.WithMessage(ErrServiceNotMongoDB, s.DisplayName()))
+	}
+	if database == "" {
+		database = s.mongoDBDefaultDatabase()
+	}
+	if !s.IsRunning() {
+		return errors.WithStack(errors.WithMessage(ErrServiceNotRunning, s.DisplayName()))
+	}
+	output.Info(fmt.Sprintf("Access shell for %s.", s.DisplayName()))
+	port, err := s.Port()
+	if err != nil {
+		return err
+	}
+	args := make([]string, 0)
+	args = append(args, "--port", fmt.Sprintf("%d", port))
+	args = append(args, s.mongoDBAuthArgs()...)
+	if database != "" {
+		args = append(args, database)
+	}
+	cmd := NewShellCommand()
+	cmd.Command = filepath.Join(GetDir(BrewDir), "opt", "mongosh", "bin", "mongosh")
+	cmd.Args = args
+	cmd.Env = ServicesEnv([]*Service{s})
+	if err := cmd.Drop(); err != nil {
+		return errors.WithStack(errors.WithMessage(err, s.DisplayName()))
+	}
+	return nil
+}
+
+// MongoDBDump dumps the given mongodb database as an archive to stdout.
+func (s *Service) MongoDBDump(database string) error {
+	if !s.IsMongoDB() {
+		return errors.WithStack(errors.WithMessage(ErrServiceNotMongoDB, s.DisplayName()))
+	}
+	if database == "" {
+		database = s.mongoDBDefaultDatabase()
+	}
+	port, err := s.Port()
+	if err != nil {
+		return err
+	}
+	cmd := NewShellCommand()
+	cmd.Command = filepath.Join(GetDir(BrewDir), "opt", "mongodb-database-tools", "bin", "mongodump")
+	cmd.Args = append([]string{"--port", fmt.Sprintf("%d", port), "--db", database, "--archive"}, s.mongoDBAuthArgs()...)
+	cmd.Env = ServicesEnv([]*Service{s})
+	if err := cmd.Drop(); err != nil {
+		return errors.WithStack(errors.WithMessage(err, s.DisplayName()))
+	}
+	return nil
+}
+
+// MongoDBExecute evaluates given javascript in the mongodb shell.
+func (s *Service) MongoDBExecute(database string, script string) error {
+	port, err := s.Port()
+	if err != nil {
+		return err
+	}
+	cmd := NewShellCommand()
+	cmd.Command = filepath.Join(GetDir(BrewDir), "opt", "mongosh", "bin", "mongosh")
+	cmd.Args = append([]string{"--quiet", "--port", fmt.Sprintf("%d", port)}, s.mongoDBAuthArgs()...)
+	cmd.Args = append(cmd.Args, database, "--eval", script)
+	cmd.Env = ServicesEnv([]*Service{s})
+	if err := cmd.Interactive(); err != nil {
+		return errors.WithStack(errors.WithMessage(err, s.DisplayName()))
+	}
+	return nil
+}
+
+// mongoDBPostSetup creates the project user and grants it access to the project databases.
+func (s *Service) mongoDBPostSetup() error {
+	if !s.IsMongoDB() {
+		return errors.WithStack(errors.WithMessage(ErrServiceNotMongoDB, s.DisplayName()))
+	}
+	if err := s.mongoDBBootstrapAdmin(); err != nil {
+		return err
+	}
+	roles := make([]string, 0)
+	for _, database := range s.MongoDBGetDatabases() {
+		database = s.project.ResolveDatabase(database)
+		output.Info(fmt.Sprintf("Grant %s database.", database))
+		roles = append(roles, fmt.Sprintf("{role: 'readWrite', db: '%s'}", database))
+	}
+	output.Info(fmt.Sprintf("Create %s user.", s.MongoDBUser()))
+	if err := s.MongoDBExecute("admin", fmt.Sprintf(
+		"if (db.getUser('%[1]s')) { db.updateUser('%[1]s', {pwd: '%[2]s', roles: [%[3]s]}) } else { db.createUser({user: '%[1]s', pwd: '%[2]s', roles: [%[3]s]}) }",
+		s.MongoDBUser(),
+		s.MongoDBPassword(),
+		strings.Join(roles, ", "),
+	)); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (s *Service) mongoDBPurge() error {
+	if !s.IsMongoDB() {
+		return errors.WithStack(errors.WithMessage(ErrServiceNotMongoDB, s.DisplayName()))
+	}
+	// needs to be running to drop databases
+	wasRunning := s.IsRunning()
+	if !wasRunning {
+		if err := s.Start(); err != nil {
+			return err
+		}
+		time.Sleep(time.Second * 3)
+	}
+	for _, database := range s.MongoDBGetDatabases() {
+		database = s.project.ResolveDatabase(database)
+		output.Info(fmt.Sprintf("Drop %s database.", database))
+		if err := s.MongoDBExecute(database, "db.dropDatabase()"); err != nil {
+			return err
+		}
+	}
+	output.Info(fmt.Sprintf("Drop %s user.", s.MongoDBUser()))
+	if err := s.MongoDBExecute("admin", fmt.Sprintf(
+		"if (db.getUser('%[1]s')) { db.dropUser('%[1]s') }", s.MongoDBUser(),
+	)); err != nil {
+		return err
+	}
+	// stop if it wasn't running
+	if !wasRunning {
+		if err := s.Stop(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
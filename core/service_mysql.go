@@ -2,6 +2,7 @@ package core
 
 import (
 	"fmt"
+	"os"
 	"path/filepath"
 	"strings"
 	"time"
@@ -20,6 +21,16 @@ func (s *Service) IsMySQL() bool {
 	return strings.HasPrefix(s.BrewAppName(), "mysql") || strings.HasPrefix(s.BrewAppName(), "mariadb")
 }
 
+// mySQLBinPath returns the path to given mysql binary, preferring the mariadb named binary when available.
+func (s *Service) mySQLBinPath(name string) string {
+	binPath := filepath.Join(GetDir(BrewDir), "opt", s.BrewAppName(), "bin")
+	mariadbName := strings.Replace(name, "mysql", "mariadb", 1)
+	if _, err := os.Stat(filepath.Join(binPath, mariadbName)); err == nil {
+		return filepath.Join(binPath, mariadbName)
+	}
+	return filepath.Join(binPath, name)
+}
+
 // MySQLGetSchemas returns list of database schemas.
 func (s *Service) MySQLGetSchemas() []string {
 	switch d := s.definition.(type) {
@@ -48,7 +59,7 @@ func (s *Service) MySQLShell(database string) error {
 		return errors.WithStack(errors.WithMessage(ErrServiceNotRunning, s.DisplayName()))
 	}
 	output.Info(fmt.Sprintf("Access shell for %s.", s.DisplayName()))
-	pathToMySQL := filepath.Join(GetDir(BrewDir), "opt", s.BrewAppName(), "bin", "mysql")
+	pathToMySQL := s.mySQLBinPath("mysql")
 	args := make([]string, 0)
 	args = append(args, "-S", s.SocketPath(), "-u", "root")
 	if database != "" {
@@ -65,7 +76,7 @@ func (s *Service) MySQLShell(database string) error {
 
 // MySQLDump dumps the given mysql database.
 func (s *Service) MySQLDump(database string) error {
-	pathToMySQL := filepath.Join(GetDir(BrewDir), "opt", s.BrewAppName(), "bin", "mysqldump")
+	pathToMySQL := s.mySQLBinPath("mysqldump")
 	cmd := NewShellCommand()
 	cmd.Command = pathToMySQL
 	cmd.Args = []string{"-S", s.SocketPath(), "-u", "root", database}
@@ -77,7 +88,7 @@ func (s *Service) MySQLDump(database string) error {
 
 // MySQLExecute executes given query.
 func (s *Service) MySQLExecute(query string) error {
-	pathToMySQL := filepath.Join(GetDir(BrewDir), "opt", s.BrewAppName(), "bin", "mysql")
+	pathToMySQL := s.mySQLBinPath("mysql")
 	cmd := NewShellCommand()
 	cmd.Command = pathToMySQL
 	cmd.Args = []string{"-S", s.SocketPath(), "-u", "root", "-e", query}
@@ -2,7 +2,6 @@ package core
 
 import (
 	"fmt"
-	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
@@ -12,43 +11,6 @@ import (
 	"gitlab.com/contextualcode/platform_cc/v2/pkg/output"
 )
 
-var loadedPHPExtensionList PHPExtensions
-
-// PHPExtensions is a map of PHP extension name to the command needed to install it.
-type PHPExtensions map[string]string
-
-// Match finds a PHP extension that matches given name.
-func (p PHPExtensions) Match(name string, version string) (string, string, error) {
-	versionName := fmt.Sprintf("%s-%s", version, name)
-	// match with php version
-	for extName, extCmd := range p {
-		if extName == versionName {
-			return extName, extCmd, nil
-		}
-	}
-	// match with just extension name
-	for extName, extCmd := range p {
-		if extName == name {
-			return extName, extCmd, nil
-		}
-	}
-	return "", "", errors.WithStack(errors.WithMessage(ErrPHPExtNotFound, name))
-}
-
-// LoadPHPExtensionList loads list of PHP extensions.
-func LoadPHPExtensionList() (PHPExtensions, error) {
-	if loadedPHPExtensionList != nil {
-		return loadedPHPExtensionList, nil
-	}
-	done := output.Duration("Load PHP extension list.")
-	loadedPHPExtensionList = make(PHPExtensions)
-	if err := loadYAML("php_ext", loadedPHPExtensionList); err != nil {
-		return nil, errors.WithStack(err)
-	}
-	done()
-	return loadedPHPExtensionList, nil
-}
-
 // IsPHP returns true if service is php.
 func (s *Service) IsPHP() bool {
 	return strings.HasPrefix(s.BrewAppName(), "php")
@@ -56,57 +18,21 @@ func (s *Service) IsPHP() bool {
 
 // PHPVersion returns the PHP version.
 func (s *Service) PHPVersion() string {
-	nameSplit := strings.Split(s.BrewAppName(), "@")
-	if len(nameSplit) < 2 {
-		return ""
-	}
-	return strings.TrimSpace(nameSplit[1])
+	return s.ActualVersion()
 }
 
-// PHPInstallExtension installs the given PHP extension.
-func (s *Service) PHPInstallExtension(name string) error {
-	if !s.IsPHP() {
-		return errors.WithStack(errors.WithMessage(ErrInvalidService, s.DisplayName()))
-	}
-	phpExtList, err := LoadPHPExtensionList()
-	if err != nil {
-		return errors.WithStack(err)
-	}
-	extKey, extCmd, err := phpExtList.Match(name, s.PHPVersion())
-	if err != nil {
-		return errors.WithStack(errors.WithMessage(err, name))
-	}
-	done := output.Duration(fmt.Sprintf("Installing PHP extension %s.", extKey))
-	cmd := NewShellCommand()
-	cmd.Args = []string{"-c", s.injectCommandParams(extCmd)}
-	cmd.Env = ServicesEnv([]*Service{s})
-	if err := cmd.Interactive(); err != nil {
-		return errors.WithStack(errors.WithMessage(err, extKey))
-	}
-	done()
-	return nil
-}
+const phpDefaultRequestMemory = 45
+const phpDefaultReservedMemory = 70
 
-// PHPGetInstalledExtensions returns list of installed PHP extensions.
-func (s *Service) PHPGetInstalledExtensions() []string {
-	if !s.IsPHP() {
-		return []string{}
-	}
-	fileInfo, err := ioutil.ReadDir(s.DataPath())
-	if err != nil {
-		output.Warn(err.Error())
-		return []string{}
-	}
-	out := make([]string, 0)
-	for _, file := range fileInfo {
-		if file.IsDir() {
-			continue
-		}
-		if filepath.Ext(file.Name()) == ".so" {
-			out = append(out, strings.Split(file.Name(), ".")[0])
-		}
-	}
-	return out
+// PHPPoolSizing defines PHP-FPM pool sizing, memory values are in MB.
+type PHPPoolSizing struct {
+	MemoryBudget    int `json:"memory_budget"`
+	RequestMemory   int `json:"request_memory"`
+	ReservedMemory  int `json:"reserved_memory"`
+	MaxChildren     int `json:"max_children"`
+	StartServers    int `json:"start_servers"`
+	MinSpareServers int `json:"min_spare_servers"`
+	MaxSpareServers int `json:"max_spare_servers"`
 }
 
 func (s *Service) phpFpmPoolPath() string {
@@ -128,20 +54,28 @@ func (s *Service) phpPreSetup() error {
 	switch d := s.definition.(type) {
 	case *def.App:
 		{
+			if d.Web.Upstream.Protocol == "http" && !s.runsAppStart() {
+				output.Warn(fmt.Sprintf(
+					"App %s uses web.upstream.protocol http without web.commands.start, PHP-FPM is used over fastcgi instead.",
+					d.Name,
+				))
+			}
 			// install extensions
-			for _, ext := range d.Runtime.Extensions {
-				if err := s.PHPInstallExtension(ext.Name); err != nil {
-					if errors.Is(err, ErrPHPExtNotFound) {
-						continue
-					}
-					return errors.WithStack(err)
-				}
+			if err := s.phpInstallRuntimeExtensions(d); err != nil {
+				return err
+			}
+			// composer
+			if err := composerFetch(composerMajorVersion(d)); err != nil {
+				output.Warn(err.Error())
 			}
 			// (re)generate config file
 			// TODO better way??
 			if err := s.GenerateConfigFile(); err != nil {
 				return err
 			}
+			if err := s.phpGenerateScanDir(); err != nil {
+				return err
+			}
 		}
 	default:
 		{
@@ -158,15 +92,75 @@ func (s *Service) phpCleanup() error {
 }
 
 func (s *Service) phpConfigParams() map[string]interface{} {
-	vars := def.Variables{}
 	projName := ""
-	if s.project != nil && s.definition != nil {
-		vars, _ = s.project.Variables(s.definition)
+	if s.project != nil {
 		projName = s.project.Name
 	}
+	appName := s.appInstanceName()
+	config, err := s.PHPConfig()
+	if err != nil {
+		output.Warn(err.Error())
+	}
+	extensions, zendExtensions := s.phpSplitZendExtensions(s.PHPEnabledExtensions())
 	return map[string]interface{}{
-		"Extensions": s.PHPGetInstalledExtensions(),
-		"Ini":        vars.GetStringSubMap("php"),
-		"Project":    projName,
+		"Extensions":     extensions,
+		"ZendExtensions": zendExtensions,
+		"Xdebug":         s.phpXdebugParams(),
+		"Ini":            config.Ini,
+		"FpmGlobal":      config.FpmGlobal,
+		"FpmPool":        config.FpmPool,
+		"Project":        projName,
+		"App":            appName,
+	}
+}
+
+// PHPPoolSizing returns the PHP-FPM pool size for the application.
+// Like Platform.sh the number of workers is the memory available, here the local memory budget,
+// minus runtime.sizing_hints.reserved_memory divided by runtime.sizing_hints.request_memory.
+func (s *Service) PHPPoolSizing() PHPPoolSizing {
+	config, err := LoadConfig()
+	if err != nil {
+		output.Warn(err.Error())
+	}
+	sizing := PHPPoolSizing{
+		MemoryBudget:   config.PHPMemoryBudget,
+		RequestMemory:  phpDefaultRequestMemory,
+		ReservedMemory: phpDefaultReservedMemory,
+	}
+	if d, ok := s.definition.(*def.App); ok {
+		raw := appYamlRaw(d)
+		if v := yamlLookupInt(raw, "runtime", "sizing_hints", "request_memory"); v > 0 {
+			sizing.RequestMemory = v
+		}
+		if v := yamlLookupInt(raw, "runtime", "sizing_hints", "reserved_memory"); v > 0 {
+			sizing.ReservedMemory = v
+		}
+	}
+	sizing.MaxChildren = (sizing.MemoryBudget - sizing.ReservedMemory) / sizing.RequestMemory
+	if sizing.MaxChildren < 1 {
+		sizing.MaxChildren = 1
+	}
+	sizing.MinSpareServers = 1
+	sizing.MaxSpareServers = 3
+	if sizing.MaxSpareServers > sizing.MaxChildren {
+		sizing.MaxSpareServers = sizing.MaxChildren
+	}
+	sizing.StartServers = 2
+	if sizing.StartServers > sizing.MaxSpareServers {
+		sizing.StartServers = sizing.MaxSpareServers
+	}
+	return sizing
+}
+
+// phpIniPath returns path to the php.ini for the application.
+func (s *Service) phpIniPath() string {
+	switch d := s.definition.(type) {
+	case *def.App:
+		{
+			if s.project != nil {
+				return filepath.Join(s.DataPath(), fmt.Sprintf("php_%s_%s.ini", s.project.Name, d.Name))
+			}
+		}
 	}
+	return filepath.Join(s.DataPath(), "php.ini")
 }
@@ -0,0 +1,211 @@
+package core
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/def"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/output"
+)
+
+const phpConfigSourceDefault = "pbrew"
+const phpConfigSourceSizing = "sizing_hints"
+const phpConfigSourceVariables = "variables"
+const phpAppIniFilename = "php.ini"
+const phpFpmOverrideFilename = ".pbrew/php-fpm.conf"
+
+// PHPConfigValue is a php.ini or PHP-FPM setting and where it came from.
+type PHPConfigValue struct {
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Source string `json:"source"`
+}
+
+// PHPConfig is the effective PHP configuration of an application.
+type PHPConfig struct {
+	Ini       []PHPConfigValue `json:"ini"`
+	FpmGlobal []PHPConfigValue `json:"fpm_global"`
+	FpmPool   []PHPConfigValue `json:"fpm_pool"`
+}
+
+// phpConfigRepeatableKeys are ini keys that can be set multiple times.
+var phpConfigRepeatableKeys = []string{"extension", "zend_extension"}
+
+// phpConfigSet sets given value, replacing an existing value with the same key.
+// Repeatable keys are appended unless the same value is already set.
+func phpConfigSet(values []PHPConfigValue, value PHPConfigValue) []PHPConfigValue {
+	repeatable := sliceContains(phpConfigRepeatableKeys, value.Key)
+	for i := range values {
+		if values[i].Key == value.Key && (!repeatable || values[i].Value == value.Value) {
+			values[i] = value
+			return values
+		}
+	}
+	return append(values, value)
+}
+
+// parsePHPConfigFile parses an ini style file in to global and section values.
+// Values in the [global] section are global, values outside of a section are global when outsideGlobal is set.
+func parsePHPConfigFile(path string, outsideGlobal bool) ([]PHPConfigValue, []PHPConfigValue, error) {
+	global := make([]PHPConfigValue, 0)
+	section := make([]PHPConfigValue, 0)
+	f, err := os.Open(path)
+	if err != nil {
+		return global, section, errors.WithStack(err)
+	}
+	defer f.Close()
+	inGlobal := outsideGlobal
+	lineNo := 0
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, ";") || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			inGlobal = strings.EqualFold(strings.Trim(line, "[]"), "global")
+			continue
+		}
+		lineSplit := strings.SplitN(line, "=", 2)
+		if len(lineSplit) != 2 {
+			continue
+		}
+		value := PHPConfigValue{
+			Key:    strings.TrimSpace(lineSplit[0]),
+			Value:  strings.TrimSpace(lineSplit[1]),
+			Source: fmt.Sprintf("%s:%d", path, lineNo),
+		}
+		if inGlobal {
+			global = phpConfigSet(global, value)
+			continue
+		}
+		section = phpConfigSet(section, value)
+	}
+	if err := scanner.Err(); err != nil {
+		return global, section, errors.WithStack(err)
+	}
+	return global, section, nil
+}
+
+// phpVariablesIni returns the php: variables of the application sorted by key.
+func (s *Service) phpVariablesIni() []PHPConfigValue {
+	out := make([]PHPConfigValue, 0)
+	if s.project == nil || s.definition == nil {
+		return out
+	}
+	vars, err := s.project.Variables(s.definition)
+	if err != nil {
+		output.Warn(err.Error())
+		return out
+	}
+	ini := vars.GetStringSubMap("php")
+	keys := make([]string, 0)
+	for key, value := range ini {
+		if value != "" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		out = append(out, PHPConfigValue{
+			Key:    key,
+			Value:  ini[key],
+			Source: fmt.Sprintf("%s (php:%s)", phpConfigSourceVariables, key),
+		})
+	}
+	return out
+}
+
+// phpConfigPaths returns the paths of given file in the application and project root.
+func (s *Service) phpConfigPaths(name string) []string {
+	out := make([]string, 0)
+	if s.project != nil {
+		out = append(out, filepath.Join(s.project.Path, name))
+	}
+	if d, ok := s.definition.(*def.App); ok {
+		appPath := filepath.Join(d.Path, name)
+		if !sliceContains(out, appPath) {
+			out = append(out, appPath)
+		}
+	}
+	return out
+}
+
+// PHPConfig returns the effective php.ini and PHP-FPM settings of the application.
+// Settings are merged in order from pbrew's defaults, php.ini in the application root,
+// .pbrew/php-fpm.conf and php: variables.
+func (s *Service) PHPConfig() (PHPConfig, error) {
+	config := PHPConfig{
+		Ini: []PHPConfigValue{
+			{Key: "date.timezone", Value: "${PHP_DATE_TIMEZONE}", Source: phpConfigSourceDefault},
+			{Key: "session.save_path", Value: "/tmp", Source: phpConfigSourceDefault},
+			{Key: "memory_limit", Value: "2048M", Source: phpConfigSourceDefault},
+		},
+		FpmGlobal: []PHPConfigValue{},
+		FpmPool:   []PHPConfigValue{},
+	}
+	if s.PHPVersion() == "5.6" {
+		config.Ini = phpConfigSet(config.Ini, PHPConfigValue{Key: "variables_order", Value: "EGPCS", Source: phpConfigSourceDefault})
+	}
+	// pool defaults
+	sizing := s.PHPPoolSizing()
+	for _, value := range []PHPConfigValue{
+		{Key: "listen", Value: s.UpstreamAddress(), Source: phpConfigSourceDefault},
+		{Key: "pm", Value: "dynamic", Source: phpConfigSourceDefault},
+		{Key: "pm.max_children", Value: fmt.Sprintf("%d", sizing.MaxChildren), Source: phpConfigSourceSizing},
+		{Key: "pm.start_servers", Value: fmt.Sprintf("%d", sizing.StartServers), Source: phpConfigSourceSizing},
+		{Key: "pm.min_spare_servers", Value: fmt.Sprintf("%d", sizing.MinSpareServers), Source: phpConfigSourceSizing},
+		{Key: "pm.max_spare_servers", Value: fmt.Sprintf("%d", sizing.MaxSpareServers), Source: phpConfigSourceSizing},
+		{Key: "clear_env", Value: "no", Source: phpConfigSourceDefault},
+	} {
+		config.FpmPool = phpConfigSet(config.FpmPool, value)
+	}
+	// php.ini in application root
+	if d, ok := s.definition.(*def.App); ok {
+		path := filepath.Join(d.Path, phpAppIniFilename)
+		if _, err := os.Stat(path); err == nil {
+			global, section, err := parsePHPConfigFile(path, true)
+			if err != nil {
+				return config, err
+			}
+			for _, value := range append(global, section...) {
+				config.Ini = phpConfigSet(config.Ini, value)
+			}
+		}
+	}
+	// .pbrew/php-fpm.conf
+	for _, path := range s.phpConfigPaths(phpFpmOverrideFilename) {
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		global, section, err := parsePHPConfigFile(path, false)
+		if err != nil {
+			return config, err
+		}
+		for _, value := range global {
+			config.FpmGlobal = phpConfigSet(config.FpmGlobal, value)
+		}
+		for _, value := range section {
+			config.FpmPool = phpConfigSet(config.FpmPool, value)
+		}
+	}
+	// php: variables
+	for _, value := range s.phpVariablesIni() {
+		config.Ini = phpConfigSet(config.Ini, value)
+		config.FpmPool = phpConfigSet(config.FpmPool, PHPConfigValue{
+			Key:    fmt.Sprintf("php_admin_value[%s]", value.Key),
+			Value:  fmt.Sprintf("\"%s\"", value.Value),
+			Source: value.Source,
+		})
+	}
+	if s.PHPVersion() == "5.6" {
+		config.FpmPool = phpConfigSet(config.FpmPool, PHPConfigValue{Key: "php_admin_value[variables_order]", Value: "\"EGPCS\"", Source: phpConfigSourceDefault})
+	}
+	return config, nil
+}
@@ -0,0 +1,605 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/def"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/output"
+)
+
+var loadedPHPExtensionList PHPExtensions
+
+// PHPExtensionVersion defines the extension version to use for a range of PHP versions.
+type PHPExtensionVersion struct {
+	PHP     string `yaml:"php"`
+	Version string `yaml:"version"`
+}
+
+// PHPExtension defines how to build a PHP extension.
+type PHPExtension struct {
+	Name         string                `yaml:"-"`
+	Versions     []PHPExtensionVersion `yaml:"versions"`
+	Dependencies []string              `yaml:"dependencies"`
+	Configure    string                `yaml:"configure"`
+	Zend         bool                  `yaml:"zend"`
+	Build        string                `yaml:"build"`
+}
+
+// PHPExtensions is a map of PHP extension name to its recipe.
+type PHPExtensions map[string]*PHPExtension
+
+// PHPExtensionStatus defines the state of a PHP extension for an application.
+type PHPExtensionStatus struct {
+	Name             string `json:"name"`
+	Version          string `json:"version"`
+	InstalledVersion string `json:"installed_version"`
+	HasRecipe        bool   `json:"has_recipe"`
+	Builtin          bool   `json:"builtin"`
+	Installed        bool   `json:"installed"`
+	Requested        bool   `json:"requested"`
+	Enabled          bool   `json:"enabled"`
+}
+
+// phpExtState stores extensions enabled or disabled for an application with php:ext.
+type phpExtState struct {
+	Enabled    []string `json:"enabled"`
+	Disabled   []string `json:"disabled"`
+	XdebugMode string   `json:"xdebug_mode,omitempty"`
+}
+
+// Match finds a PHP extension that matches given name.
+func (p PHPExtensions) Match(name string) (*PHPExtension, error) {
+	ext := p[name]
+	if ext == nil {
+		return nil, errors.WithStack(errors.WithMessage(ErrPHPExtNotFound, name))
+	}
+	return ext, nil
+}
+
+// VersionFor returns the extension version to build for given PHP version.
+func (e *PHPExtension) VersionFor(phpVersion string) (string, error) {
+	for _, v := range e.Versions {
+		if matchVersionConstraint(phpVersion, v.PHP) {
+			return v.Version, nil
+		}
+	}
+	return "", errors.WithStack(errors.WithMessage(ErrPHPExtVersionNotSupported, fmt.Sprintf("%s (PHP %s)", e.Name, phpVersion)))
+}
+
+// LoadPHPExtensionList loads list of PHP extensions.
+func LoadPHPExtensionList() (PHPExtensions, error) {
+	if loadedPHPExtensionList != nil {
+		return loadedPHPExtensionList, nil
+	}
+	done := output.Duration("Load PHP extension list.")
+	loadedPHPExtensionList = make(PHPExtensions)
+	if err := loadYAML("php_ext", loadedPHPExtensionList); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	for name, ext := range loadedPHPExtensionList {
+		ext.Name = name
+	}
+	done()
+	return loadedPHPExtensionList, nil
+}
+
+// matchVersionConstraint returns true if version satisfies every space separated constraint.
+// Constraints are either a comparison (>=7.2, <8.0) or a version prefix (7.4, 8.*).
+func matchVersionConstraint(version string, constraint string) bool {
+	for _, c := range strings.Fields(constraint) {
+		op := c[0 : len(c)-len(strings.TrimLeft(c, "<>=!"))]
+		target := strings.TrimPrefix(c, op)
+		cmp := compareVersions(version, target)
+		switch op {
+		case ">=":
+			if cmp < 0 {
+				return false
+			}
+		case "<=":
+			if cmp > 0 {
+				return false
+			}
+		case ">":
+			if cmp <= 0 {
+				return false
+			}
+		case "<":
+			if cmp >= 0 {
+				return false
+			}
+		case "!=":
+			if cmp == 0 {
+				return false
+			}
+		default:
+			target = strings.TrimSuffix(strings.TrimSuffix(target, "*"), ".")
+			if target != "" && version != target && !strings.HasPrefix(version, target+".") {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// phpExtPath returns path to the installed module of given extension.
+func (s *Service) phpExtPath(name string) string {
+	return filepath.Join(s.DataPath(), name+".so")
+}
+
+// phpExtCachePath returns path to the cached build of given extension version.
+func (s *Service) phpExtCachePath(name string, version string) string {
+	return filepath.Join(s.DataPath(), "ext", fmt.Sprintf("%s-%s.so", name, version))
+}
+
+// PHPInstallExtension installs the given PHP extension.
+func (s *Service) PHPInstallExtension(name string) error {
+	if !s.IsPHP() {
+		return errors.WithStack(errors.WithMessage(ErrInvalidService, s.DisplayName()))
+	}
+	phpExtList, err := LoadPHPExtensionList()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	ext, err := phpExtList.Match(name)
+	if err != nil {
+		return err
+	}
+	version, err := ext.VersionFor(s.PHPVersion())
+	if err != nil {
+		return err
+	}
+	extPath := s.phpExtPath(name)
+	installedVersion, installed := s.PHPInstalledExtensionVersion(name)
+	if installed && (installedVersion == version || installedVersion == "") {
+		return nil
+	}
+	done := output.Duration(fmt.Sprintf("Installing PHP extension %s %s.", name, version))
+	cachePath := s.phpExtCachePath(name, version)
+	if _, err := os.Stat(cachePath); err != nil {
+		if !os.IsNotExist(err) {
+			return errors.WithStack(err)
+		}
+		if err := s.phpBuildExtension(ext, version); err != nil {
+			return err
+		}
+	} else {
+		output.LogInfo(fmt.Sprintf("Use cached build %s.", cachePath))
+	}
+	if err := os.Remove(extPath); err != nil && !os.IsNotExist(err) {
+		return errors.WithStack(err)
+	}
+	if err := os.Symlink(cachePath, extPath); err != nil {
+		return errors.WithStack(err)
+	}
+	done()
+	return nil
+}
+
+// phpBuildExtension builds given extension version in to the build cache.
+func (s *Service) phpBuildExtension(ext *PHPExtension, version string) error {
+	for _, name := range ext.Dependencies {
+		dependService := Service{BrewName: name}
+		if dependService.IsInstalled() {
+			continue
+		}
+		if err := dependService.Install(); err != nil {
+			return err
+		}
+	}
+	cachePath := s.phpExtCachePath(ext.Name, version)
+	if err := os.MkdirAll(filepath.Dir(cachePath), mkdirPerm); err != nil {
+		return errors.WithStack(err)
+	}
+	extCmd := ext.Build
+	for k, v := range map[string]string{
+		"{EXT_CONFIGURE}":  ext.Configure,
+		"{EXT_NAME}":       ext.Name,
+		"{EXT_VERSION}":    version,
+		"{EXT_SO}":         cachePath,
+		"{EXT_SOURCE}":     filepath.Join(GetDir(BottleDir), "pecl", fmt.Sprintf("%s-%s.tgz", ext.Name, version)),
+		"{EXT_BUILD_PATH}": filepath.Join(GetDir(TempDir), fmt.Sprintf("%s-%s-%s", strings.ReplaceAll(s.BrewAppName(), "@", "-"), ext.Name, version)),
+	} {
+		extCmd = strings.ReplaceAll(extCmd, k, v)
+	}
+	cmd := NewShellCommand()
+	cmd.Args = []string{"-c", s.injectCommandParams(extCmd)}
+	cmd.Env = ServicesEnv([]*Service{s})
+	if err := cmd.Interactive(); err != nil {
+		os.Remove(cachePath)
+		return errors.WithStack(errors.WithMessage(err, ext.Name))
+	}
+	if _, err := os.Stat(cachePath); err != nil {
+		return errors.WithStack(errors.WithMessage(ErrPHPExtNotInstalled, ext.Name))
+	}
+	return nil
+}
+
+// PHPRemoveExtension removes the given PHP extension, the cached build is kept.
+func (s *Service) PHPRemoveExtension(name string) error {
+	if !s.IsPHP() {
+		return errors.WithStack(errors.WithMessage(ErrInvalidService, s.DisplayName()))
+	}
+	if err := os.Remove(s.phpExtPath(name)); err != nil {
+		if os.IsNotExist(err) {
+			return errors.WithStack(errors.WithMessage(ErrPHPExtNotInstalled, name))
+		}
+		return errors.WithStack(err)
+	}
+	output.Info(fmt.Sprintf("Removed PHP extension %s.", name))
+	return nil
+}
+
+// PHPInstalledExtensionVersion returns the installed version of given extension.
+// The version is empty for extensions that weren't installed from the build cache.
+func (s *Service) PHPInstalledExtensionVersion(name string) (string, bool) {
+	extPath := s.phpExtPath(name)
+	if _, err := os.Stat(extPath); err != nil {
+		return "", false
+	}
+	target, err := os.Readlink(extPath)
+	if err != nil {
+		return "", true
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(filepath.Base(target), name+"-"), ".so"), true
+}
+
+// PHPGetInstalledExtensions returns list of installed PHP extensions.
+func (s *Service) PHPGetInstalledExtensions() []string {
+	if !s.IsPHP() {
+		return []string{}
+	}
+	fileInfo, err := ioutil.ReadDir(s.DataPath())
+	if err != nil {
+		output.Warn(err.Error())
+		return []string{}
+	}
+	out := make([]string, 0)
+	for _, file := range fileInfo {
+		if file.IsDir() {
+			continue
+		}
+		if filepath.Ext(file.Name()) == ".so" {
+			out = append(out, strings.Split(file.Name(), ".")[0])
+		}
+	}
+	return out
+}
+
+// PHPRequestedExtensions returns the extensions listed in the application's runtime.extensions.
+func (s *Service) PHPRequestedExtensions() []string {
+	out := make([]string, 0)
+	switch d := s.definition.(type) {
+	case *def.App:
+		{
+			for _, ext := range d.Runtime.Extensions {
+				out = append(out, ext.Name)
+			}
+		}
+	}
+	return out
+}
+
+// PHPBuiltinExtensions returns the extensions available with the Homebrew PHP install.
+func (s *Service) PHPBuiltinExtensions() []string {
+	cmd := exec.Command(filepath.Join(GetDir(BrewDir), "opt", s.BrewAppName(), "bin", "php"), "-m")
+	cmd.Env = brewEnv()
+	raw, err := cmd.Output()
+	if err != nil {
+		output.LogWarn(err.Error())
+		return []string{}
+	}
+	out := make([]string, 0)
+	scanner := bufio.NewScanner(bytes.NewReader(raw))
+	for scanner.Scan() {
+		line := strings.ToLower(strings.TrimSpace(scanner.Text()))
+		if line == "" || strings.HasPrefix(line, "[") {
+			continue
+		}
+		out = append(out, strings.ReplaceAll(strings.TrimPrefix(line, "zend "), " ", "_"))
+	}
+	return out
+}
+
+// PHPEnabledExtensions returns the installed extensions to load for the application.
+// These are the requested extensions plus the ones enabled with php:ext, minus the disabled ones.
+func (s *Service) PHPEnabledExtensions() []string {
+	installed := s.PHPGetInstalledExtensions()
+	if _, ok := s.definition.(*def.App); !ok || s.project == nil {
+		return installed
+	}
+	state, err := s.phpLoadExtState()
+	if err != nil {
+		output.Warn(err.Error())
+	}
+	out := make([]string, 0)
+	for _, name := range installed {
+		if sliceContains(state.Enabled, name) {
+			out = append(out, name)
+			continue
+		}
+		if sliceContains(state.Disabled, name) || sliceContains(s.PHPDisabledExtensions(), name) {
+			continue
+		}
+		if sliceContains(s.PHPRequestedExtensions(), name) {
+			out = append(out, name)
+		}
+	}
+	return out
+}
+
+// PHPDisabledExtensions returns the extensions listed in the application's runtime.disabled_extensions.
+func (s *Service) PHPDisabledExtensions() []string {
+	if d, ok := s.definition.(*def.App); ok {
+		return d.Runtime.DisabledExtensions
+	}
+	return []string{}
+}
+
+// phpScanDirPath returns the path to the application's copy of PHP's conf.d directory.
+func (s *Service) phpScanDirPath() string {
+	return strings.TrimSuffix(s.phpIniPath(), ".ini") + "_conf.d"
+}
+
+// phpGenerateScanDir copies PHP's conf.d directory for the application, leaving out
+// the ini files that load disabled extensions.
+func (s *Service) phpGenerateScanDir() error {
+	scanDirPath := s.phpScanDirPath()
+	if err := os.RemoveAll(scanDirPath); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := os.MkdirAll(scanDirPath, mkdirPerm); err != nil {
+		return errors.WithStack(err)
+	}
+	disabled := s.PHPDisabledExtensions()
+	if state, err := s.phpLoadExtState(); err == nil {
+		disabled = append(disabled, state.Disabled...)
+		for _, name := range state.Enabled {
+			disabled = sliceRemove(disabled, name)
+		}
+	}
+	confDPath := filepath.Join(GetDir(BrewDir), "etc", "php", s.PHPVersion(), "conf.d")
+	fileInfo, err := ioutil.ReadDir(confDPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return errors.WithStack(err)
+	}
+	for _, file := range fileInfo {
+		if file.IsDir() || filepath.Ext(file.Name()) != ".ini" {
+			continue
+		}
+		raw, err := ioutil.ReadFile(filepath.Join(confDPath, file.Name()))
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if name := phpIniLoadedExtension(string(raw)); name != "" && sliceContains(disabled, name) {
+			output.LogInfo(fmt.Sprintf("Disable PHP extension %s.", name))
+			continue
+		}
+		if err := ioutil.WriteFile(filepath.Join(scanDirPath, file.Name()), raw, 0644); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
+}
+
+// phpIniLoadedExtension returns the name of the extension loaded by given ini file contents.
+func phpIniLoadedExtension(ini string) string {
+	for _, line := range strings.Split(ini, "\n") {
+		lineSplit := strings.SplitN(strings.TrimSpace(line), "=", 2)
+		if len(lineSplit) != 2 {
+			continue
+		}
+		key := strings.TrimSpace(lineSplit[0])
+		if key != "extension" && key != "zend_extension" {
+			continue
+		}
+		name := strings.Trim(strings.TrimSpace(lineSplit[1]), "\"'")
+		name = strings.TrimSuffix(filepath.Base(name), ".so")
+		return strings.TrimPrefix(strings.ToLower(name), "php_")
+	}
+	return ""
+}
+
+// PHPEnableExtension enables given extension for the application, installing it if needed.
+func (s *Service) PHPEnableExtension(name string) error {
+	if _, installed := s.PHPInstalledExtensionVersion(name); !installed {
+		if err := s.PHPInstallExtension(name); err != nil {
+			return err
+		}
+	}
+	state, err := s.phpLoadExtState()
+	if err != nil {
+		return err
+	}
+	state.Disabled = sliceRemove(state.Disabled, name)
+	if !sliceContains(state.Enabled, name) && !sliceContains(s.PHPRequestedExtensions(), name) {
+		state.Enabled = append(state.Enabled, name)
+	}
+	output.Info(fmt.Sprintf("Enabled PHP extension %s.", name))
+	return s.phpSaveExtState(state)
+}
+
+// PHPDisableExtension disables given extension for the application.
+func (s *Service) PHPDisableExtension(name string) error {
+	state, err := s.phpLoadExtState()
+	if err != nil {
+		return err
+	}
+	state.Enabled = sliceRemove(state.Enabled, name)
+	if !sliceContains(state.Disabled, name) {
+		state.Disabled = append(state.Disabled, name)
+	}
+	output.Info(fmt.Sprintf("Disabled PHP extension %s.", name))
+	return s.phpSaveExtState(state)
+}
+
+// PHPExtensionStatuses returns the status of every known, requested or installed extension.
+func (s *Service) PHPExtensionStatuses() ([]PHPExtensionStatus, error) {
+	phpExtList, err := LoadPHPExtensionList()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0)
+	for name := range phpExtList {
+		names = append(names, name)
+	}
+	for _, name := range append(s.PHPRequestedExtensions(), s.PHPGetInstalledExtensions()...) {
+		if !sliceContains(names, name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	builtin := s.PHPBuiltinExtensions()
+	enabled := s.PHPEnabledExtensions()
+	out := make([]PHPExtensionStatus, 0)
+	for _, name := range names {
+		status := PHPExtensionStatus{
+			Name:      name,
+			Builtin:   sliceContains(builtin, name),
+			Requested: sliceContains(s.PHPRequestedExtensions(), name),
+			Enabled:   sliceContains(enabled, name),
+		}
+		if ext := phpExtList[name]; ext != nil {
+			status.HasRecipe = true
+			status.Version, _ = ext.VersionFor(s.PHPVersion())
+		}
+		status.InstalledVersion, status.Installed = s.PHPInstalledExtensionVersion(name)
+		if status.Builtin && !sliceContains(s.PHPDisabledExtensions(), name) {
+			status.Enabled = true
+		}
+		out = append(out, status)
+	}
+	return out, nil
+}
+
+// PHPMissingExtensions returns the requested extensions that are neither built in nor have a recipe for the PHP version.
+func (s *Service) PHPMissingExtensions() ([]string, error) {
+	phpExtList, err := LoadPHPExtensionList()
+	if err != nil {
+		return nil, err
+	}
+	var builtin []string
+	out := make([]string, 0)
+	for _, name := range s.PHPRequestedExtensions() {
+		if ext := phpExtList[name]; ext != nil {
+			if _, err := ext.VersionFor(s.PHPVersion()); err == nil {
+				continue
+			}
+		}
+		if builtin == nil {
+			builtin = s.PHPBuiltinExtensions()
+		}
+		if !sliceContains(builtin, name) {
+			out = append(out, name)
+		}
+	}
+	return out, nil
+}
+
+// PHPApplyExtensions regenerates the application's php.ini and reloads PHP if it's running.
+func (s *Service) PHPApplyExtensions() error {
+	if err := s.GenerateConfigFile(); err != nil {
+		return err
+	}
+	if err := s.phpGenerateScanDir(); err != nil {
+		return err
+	}
+	if !s.IsRunning() {
+		return nil
+	}
+	return s.Reload()
+}
+
+// phpInstallRuntimeExtensions installs the application's requested extensions and reports the ones without a recipe.
+func (s *Service) phpInstallRuntimeExtensions(d *def.App) error {
+	missing, err := s.PHPMissingExtensions()
+	if err != nil {
+		return err
+	}
+	for _, ext := range d.Runtime.Extensions {
+		if sliceContains(missing, ext.Name) {
+			continue
+		}
+		if err := s.PHPInstallExtension(ext.Name); err != nil {
+			if errors.Is(err, ErrPHPExtNotFound) {
+				continue
+			}
+			return errors.WithStack(err)
+		}
+	}
+	if len(missing) > 0 {
+		output.Warn(fmt.Sprintf(
+			"No recipe for PHP %s extension(s) requested by %s: %s.",
+			s.PHPVersion(), d.Name, strings.Join(missing, ", "),
+		))
+	}
+	return nil
+}
+
+// phpSplitZendExtensions splits given extensions in to regular and zend extensions.
+func (s *Service) phpSplitZendExtensions(names []string) ([]string, []string) {
+	phpExtList, err := LoadPHPExtensionList()
+	if err != nil {
+		output.Warn(err.Error())
+	}
+	extensions := make([]string, 0)
+	zendExtensions := make([]string, 0)
+	for _, name := range names {
+		if ext := phpExtList[name]; ext != nil && ext.Zend {
+			zendExtensions = append(zendExtensions, name)
+			continue
+		}
+		extensions = append(extensions, name)
+	}
+	return extensions, zendExtensions
+}
+
+func (s *Service) phpExtStatePath() string {
+	appName := ""
+	if d, ok := s.definition.(*def.App); ok {
+		appName = d.Name
+	}
+	projName := ""
+	if s.project != nil {
+		projName = s.project.Name
+	}
+	return filepath.Join(s.DataPath(), fmt.Sprintf("pbrew_ext_%s_%s.json", projName, appName))
+}
+
+func (s *Service) phpLoadExtState() (phpExtState, error) {
+	state := phpExtState{Enabled: []string{}, Disabled: []string{}}
+	raw, err := ioutil.ReadFile(s.phpExtStatePath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return state, nil
+		}
+		return state, errors.WithStack(err)
+	}
+	if err := json.Unmarshal(raw, &state); err != nil {
+		return state, errors.WithStack(err)
+	}
+	return state, nil
+}
+
+func (s *Service) phpSaveExtState(state phpExtState) error {
+	raw, err := json.Marshal(state)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if err := ioutil.WriteFile(s.phpExtStatePath(), raw, 0644); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
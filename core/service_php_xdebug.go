@@ -0,0 +1,96 @@
+package core
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/output"
+)
+
+const phpXdebugExtName = "xdebug"
+const phpXdebugDefaultMode = "debug"
+
+var phpXdebugModes = []string{"off", "develop", "coverage", "debug", "gcstats", "profile", "trace"}
+
+// PHPXdebugStatus defines the xdebug state for an application.
+type PHPXdebugStatus struct {
+	Enabled    bool   `json:"enabled"`
+	Installed  bool   `json:"installed"`
+	Version    string `json:"version"`
+	Mode       string `json:"mode"`
+	ClientHost string `json:"client_host"`
+	ClientPort int    `json:"client_port"`
+}
+
+// phpXdebugTemplateParams defines the xdebug settings used in php.ini.tmpl.
+type phpXdebugTemplateParams struct {
+	Mode       string
+	ClientHost string
+	ClientPort int
+	Legacy     bool
+}
+
+// PHPXdebugOn enables xdebug for the application with given comma separated modes.
+func (s *Service) PHPXdebugOn(mode string) error {
+	if mode == "" {
+		mode = phpXdebugDefaultMode
+	}
+	for _, m := range strings.Split(mode, ",") {
+		if !sliceContains(phpXdebugModes, strings.TrimSpace(m)) {
+			return errors.WithStack(errors.WithMessage(ErrInvalidXdebugMode, m))
+		}
+	}
+	if err := s.PHPEnableExtension(phpXdebugExtName); err != nil {
+		return err
+	}
+	state, err := s.phpLoadExtState()
+	if err != nil {
+		return err
+	}
+	state.XdebugMode = strings.ReplaceAll(mode, " ", "")
+	return s.phpSaveExtState(state)
+}
+
+// PHPXdebugOff disables xdebug for the application.
+func (s *Service) PHPXdebugOff() error {
+	return s.PHPDisableExtension(phpXdebugExtName)
+}
+
+// PHPXdebugStatus returns the xdebug state for the application.
+func (s *Service) PHPXdebugStatus() PHPXdebugStatus {
+	params := s.phpXdebugParams()
+	version, installed := s.PHPInstalledExtensionVersion(phpXdebugExtName)
+	return PHPXdebugStatus{
+		Enabled:    sliceContains(s.PHPEnabledExtensions(), phpXdebugExtName),
+		Installed:  installed,
+		Version:    version,
+		Mode:       params.Mode,
+		ClientHost: params.ClientHost,
+		ClientPort: params.ClientPort,
+	}
+}
+
+// phpXdebugParams returns the xdebug settings for the application.
+func (s *Service) phpXdebugParams() phpXdebugTemplateParams {
+	config, err := LoadConfig()
+	if err != nil {
+		output.Warn(err.Error())
+	}
+	params := phpXdebugTemplateParams{
+		Mode:       phpXdebugDefaultMode,
+		ClientHost: config.XdebugClientHost,
+		ClientPort: config.XdebugClientPort,
+	}
+	if state, err := s.phpLoadExtState(); err == nil && state.XdebugMode != "" {
+		params.Mode = state.XdebugMode
+	}
+	// xdebug 2 uses the remote_* settings and has no modes
+	version, _ := s.PHPInstalledExtensionVersion(phpXdebugExtName)
+	if version == "" {
+		if phpExtList, err := LoadPHPExtensionList(); err == nil && phpExtList[phpXdebugExtName] != nil {
+			version, _ = phpExtList[phpXdebugExtName].VersionFor(s.PHPVersion())
+		}
+	}
+	params.Legacy = version != "" && compareVersions(version, "3") < 0
+	return params
+}
@@ -4,15 +4,19 @@ import (
 	"archive/tar"
 	"bytes"
 	"compress/gzip"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
 	"time"
 
@@ -71,54 +75,154 @@ func (s *Service) SolrAddConfigSets() error {
 	return nil
 }
 
-// SolrAddCores adds all solr cores for given project and service.
-func (s *Service) SolrAddCores() error {
+// SolrCoreStatus defines the state of a solr core for a project.
+type SolrCoreStatus struct {
+	Name          string `json:"name"`
+	Loaded        bool   `json:"loaded"`
+	ConfigChanged bool   `json:"config_changed"`
+	Defined       bool   `json:"defined"`
+}
+
+var solrConfigSetRegex = regexp.MustCompile(`(?m)configSet\=[ ]*(.*)`)
+
+// SolrSyncCores reconciles solr cores with the service definition. Missing cores are created,
+// cores with changed configuration are updated and reloaded in place and cores that are no longer
+// defined are unloaded, keeping their data. When force is true every defined core is updated and reloaded.
+func (s *Service) SolrSyncCores(force bool) error {
 	if !s.IsSolr() {
 		return errors.WithStack(errors.WithMessage(ErrServiceNotSolr, s.DisplayName()))
 	}
-	switch d := s.definition.(type) {
-	case *def.Service:
-		{
-			for core, conf := range d.Configuration["cores"].(map[string]interface{}) {
-				if s.SolrHasCore(core) {
-					output.Info(fmt.Sprintf("Core %s already exists.", core))
-					return nil
-				}
-				output.Info(fmt.Sprintf("Create core %s.", core))
-				args := make([]string, 0)
-				args = append(args, "-c", s.SolrCoreName(core))
-				if conf.(map[string]interface{})["conf_dir"] != nil {
-					if err := s.solrExtactConfigDir(conf.(map[string]interface{})["conf_dir"].(string)); err != nil {
-						return err
-					}
-					args = append(args, "-d", s.solrGetTempDir())
-				}
-				if _, err := s.solrCommand("create_core", args...); err != nil {
+	cores, err := s.solrDefinedCores()
+	if err != nil {
+		return err
+	}
+	state, err := s.solrLoadCoreState(cores)
+	if err != nil {
+		return err
+	}
+	names := make([]string, 0)
+	for core := range cores {
+		names = append(names, core)
+	}
+	sort.Strings(names)
+	for _, core := range names {
+		conf := cores[core]
+		coreName := s.SolrCoreName(core)
+		hash, err := s.solrCoreConfigHash(conf)
+		if err != nil {
+			return err
+		}
+		if !s.SolrHasCore(core) {
+			output.Info(fmt.Sprintf("Create core %s.", core))
+			args := make([]string, 0)
+			args = append(args, "-c", coreName)
+			if conf["conf_dir"] != nil {
+				args = append(args, "-d", s.solrGetTempDir())
+			}
+			if _, err := s.solrCommand("create_core", args...); err != nil {
+				return err
+			}
+			if conf["core_properties"] != nil {
+				time.Sleep(time.Second)
+				if err := s.solrWriteCoreProperties(core, conf["core_properties"].(string)); err != nil {
 					return err
 				}
-				if conf.(map[string]interface{})["core_properties"] != nil {
-					time.Sleep(time.Second)
-					corePropPath := filepath.Join(s.DataPath(), s.SolrCoreName(core), "core.properties")
-					coreProps := fmt.Sprintf("name=%s\n", s.SolrCoreName(core)) + conf.(map[string]interface{})["core_properties"].(string)
-					re := regexp.MustCompile(`(?m)configSet\=[ ]*(.*)`)
-					coreProps = re.ReplaceAllStringFunc(coreProps, func(m string) string {
-						configSetName := strings.TrimSpace(strings.Split(m, "=")[1])
-						return fmt.Sprintf("configSet=%s", s.SolrCoreName(configSetName))
-					})
-					if err := ioutil.WriteFile(
-						corePropPath, []byte(coreProps), 0755,
-					); err != nil {
-						return errors.WithStack(errors.WithMessage(err, s.DisplayName()))
-					}
+				if err := s.solrCoreAdmin("RELOAD", coreName); err != nil {
+					return err
 				}
 			}
+			state[coreName] = hash
+			continue
 		}
-	default:
-		{
-			return errors.WithStack(errors.WithMessage(ErrServiceDefNotDefined, s.DisplayName()))
+		if !force && state[coreName] == hash {
+			output.Info(fmt.Sprintf("Core %s already exists.", core))
+			continue
 		}
+		output.Info(fmt.Sprintf("Update core %s.", core))
+		if conf["conf_dir"] != nil {
+			confPath := filepath.Join(s.DataPath(), coreName, "conf")
+			os.RemoveAll(confPath)
+			if err := os.Rename(s.solrGetTempDir(), confPath); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+		if conf["core_properties"] != nil {
+			if err := s.solrWriteCoreProperties(core, conf["core_properties"].(string)); err != nil {
+				return err
+			}
+		}
+		if err := s.solrCoreAdmin("RELOAD", coreName); err != nil {
+			return err
+		}
+		state[coreName] = hash
 	}
-	return nil
+	// unload cores no longer defined
+	for coreName := range state {
+		isDefined := false
+		for core := range cores {
+			if s.SolrCoreName(core) == coreName {
+				isDefined = true
+				break
+			}
+		}
+		if isDefined {
+			continue
+		}
+		output.Info(fmt.Sprintf("Unload core %s.", coreName))
+		if err := s.solrCoreAdmin("UNLOAD", coreName); err != nil {
+			output.Warn(err.Error())
+		}
+		delete(state, coreName)
+	}
+	return s.solrSaveCoreState(state)
+}
+
+// SolrCoreStatuses returns the status of every core defined or previously created for the project.
+func (s *Service) SolrCoreStatuses() ([]SolrCoreStatus, error) {
+	if !s.IsSolr() {
+		return nil, errors.WithStack(errors.WithMessage(ErrServiceNotSolr, s.DisplayName()))
+	}
+	cores, err := s.solrDefinedCores()
+	if err != nil {
+		return nil, err
+	}
+	state, err := s.solrLoadCoreState(cores)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]SolrCoreStatus, 0)
+	for core, conf := range cores {
+		coreName := s.SolrCoreName(core)
+		hash, err := s.solrCoreConfigHash(conf)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, SolrCoreStatus{
+			Name:          coreName,
+			Loaded:        s.SolrHasCore(core),
+			ConfigChanged: state[coreName] != hash,
+			Defined:       true,
+		})
+	}
+	for coreName := range state {
+		isDefined := false
+		for _, status := range out {
+			if status.Name == coreName {
+				isDefined = true
+				break
+			}
+		}
+		if !isDefined {
+			out = append(out, SolrCoreStatus{
+				Name:   coreName,
+				Loaded: s.solrHasCoreName(coreName),
+			})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return strings.Compare(out[i].Name, out[j].Name) < 0
+	})
+	return out, nil
 }
 
 // SolrCoreName returns the name of a given solr core in reference to given project.
@@ -131,18 +235,22 @@ func (s *Service) SolrCoreName(core string) string {
 
 // SolrHasCore check if solr already has given core.
 func (s *Service) SolrHasCore(core string) bool {
+	return s.solrHasCoreName(s.SolrCoreName(core))
+}
+
+func (s *Service) solrHasCoreName(core string) bool {
 	port, _ := s.Port()
 	if port == 0 {
 		return false
 	}
-	core = s.SolrCoreName(core)
 	resp, err := http.Get(fmt.Sprintf(
 		"http://localhost:%d/solr/admin/cores?action=STATUS&wt=json&core=%s",
-		port, core,
+		port, url.QueryEscape(core),
 	))
 	if err != nil {
 		return false
 	}
+	defer resp.Body.Close()
 	rawResp, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
 		return false
@@ -151,8 +259,165 @@ func (s *Service) SolrHasCore(core string) bool {
 	if err := json.Unmarshal(rawResp, &coreStatusData); err != nil {
 		return false
 	}
-	return coreStatusData["status"].(map[string]interface{})[core].(map[string]interface{})["name"] != nil
+	status, _ := coreStatusData["status"].(map[string]interface{})
+	coreStatus, _ := status[core].(map[string]interface{})
+	return coreStatus != nil && coreStatus["name"] != nil
+}
 
+// solrCoreAdmin performs a core admin action on given core.
+func (s *Service) solrCoreAdmin(action string, core string) error {
+	port, err := s.Port()
+	if err != nil {
+		return err
+	}
+	params := url.Values{}
+	params.Set("action", action)
+	params.Set("core", core)
+	params.Set("wt", "json")
+	resp, err := http.Get(fmt.Sprintf(
+		"http://localhost:%d/solr/admin/cores?%s", port, params.Encode(),
+	))
+	if err != nil {
+		return errors.WithStack(errors.WithMessage(err, s.DisplayName()))
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		rawResp, _ := ioutil.ReadAll(resp.Body)
+		return errors.WithStack(errors.WithMessage(
+			ErrSolrCoreAdmin, fmt.Sprintf("%s %s: %s", action, core, strings.TrimSpace(string(rawResp))),
+		))
+	}
+	return nil
+}
+
+// solrDefinedCores returns the cores defined by every solr service in the project that share this service.
+func (s *Service) solrDefinedCores() (map[string]map[string]interface{}, error) {
+	defs := make([]def.Service, 0)
+	switch d := s.definition.(type) {
+	case *def.Service:
+		{
+			defs = append(defs, *d)
+			break
+		}
+	default:
+		{
+			return nil, errors.WithStack(errors.WithMessage(ErrServiceDefNotDefined, s.DisplayName()))
+		}
+	}
+	// other services in the project that run on the same solr instance
+	if s.project != nil {
+		serviceList, err := LoadServiceList()
+		if err != nil {
+			return nil, err
+		}
+		for _, pshs := range s.project.Services {
+			if pshs.Name == defs[0].Name {
+				continue
+			}
+			brewService, err := serviceList.MatchDef(pshs)
+			if err != nil || brewService.Name != s.Name {
+				continue
+			}
+			defs = append(defs, pshs)
+		}
+	}
+	out := make(map[string]map[string]interface{})
+	for _, d := range defs {
+		cores, _ := d.Configuration["cores"].(map[string]interface{})
+		for core, conf := range cores {
+			confMap, _ := conf.(map[string]interface{})
+			if confMap == nil {
+				confMap = make(map[string]interface{})
+			}
+			out[core] = confMap
+		}
+	}
+	return out, nil
+}
+
+// solrCoreConfigHash extracts core config and returns a hash of it and any config sets it references.
+func (s *Service) solrCoreConfigHash(conf map[string]interface{}) (string, error) {
+	h := sha256.New()
+	if conf["conf_dir"] != nil {
+		if err := s.solrExtactConfigDir(conf["conf_dir"].(string)); err != nil {
+			return "", err
+		}
+		if err := hashDir(h, s.solrGetTempDir()); err != nil {
+			return "", err
+		}
+	}
+	if conf["core_properties"] != nil {
+		coreProps := conf["core_properties"].(string)
+		io.WriteString(h, coreProps)
+		for _, m := range solrConfigSetRegex.FindAllStringSubmatch(coreProps, -1) {
+			configSetPath := filepath.Join(s.DataPath(), "configsets", s.SolrCoreName(strings.TrimSpace(m[1])))
+			if err := hashDir(h, configSetPath); err != nil {
+				return "", err
+			}
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func (s *Service) solrWriteCoreProperties(core string, props string) error {
+	corePropPath := filepath.Join(s.DataPath(), s.SolrCoreName(core), "core.properties")
+	coreProps := fmt.Sprintf("name=%s\n", s.SolrCoreName(core)) + props
+	coreProps = solrConfigSetRegex.ReplaceAllStringFunc(coreProps, func(m string) string {
+		configSetName := strings.TrimSpace(strings.Split(m, "=")[1])
+		return fmt.Sprintf("configSet=%s", s.SolrCoreName(configSetName))
+	})
+	if err := ioutil.WriteFile(
+		corePropPath, []byte(coreProps), 0755,
+	); err != nil {
+		return errors.WithStack(errors.WithMessage(err, s.DisplayName()))
+	}
+	return nil
+}
+
+func (s *Service) solrCoreStatePath() string {
+	projName := ""
+	if s.project != nil {
+		projName = s.project.Name
+	}
+	return filepath.Join(s.DataPath(), fmt.Sprintf("pbrew_cores_%s.json", projName))
+}
+
+// solrLoadCoreState loads the config hash of every core synced for the project. Without a state file
+// the defined cores that already exist are assumed to be up to date, so they aren't needlessly reloaded.
+func (s *Service) solrLoadCoreState(cores map[string]map[string]interface{}) (map[string]string, error) {
+	out := make(map[string]string)
+	raw, err := ioutil.ReadFile(s.solrCoreStatePath())
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			return nil, errors.WithStack(err)
+		}
+		for core, conf := range cores {
+			if !s.SolrHasCore(core) {
+				continue
+			}
+			hash, err := s.solrCoreConfigHash(conf)
+			if err != nil {
+				return nil, err
+			}
+			out[s.SolrCoreName(core)] = hash
+		}
+		return out, nil
+	}
+	if err := json.Unmarshal(raw, &out); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return out, nil
+}
+
+func (s *Service) solrSaveCoreState(state map[string]string) error {
+	raw, err := json.Marshal(state)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if err := ioutil.WriteFile(s.solrCoreStatePath(), raw, mkdirPerm); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
 }
 
 func (s *Service) solrCommand(cmdStr string, args ...string) ([]byte, error) {
@@ -178,7 +443,7 @@ func (s *Service) solrPostSetup() error {
 	if err := s.SolrAddConfigSets(); err != nil {
 		return err
 	}
-	if err := s.SolrAddCores(); err != nil {
+	if err := s.SolrSyncCores(false); err != nil {
 		return err
 	}
 	return nil
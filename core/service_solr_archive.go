@@ -0,0 +1,275 @@
+package core
+
+import (
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/output"
+)
+
+const solrDefaultMirror = "https://archive.apache.org/dist"
+
+// solrChecksumMirrors are the Apache hosts checksums are fetched from, never from the configured mirror.
+var solrChecksumMirrors = []string{"https://downloads.apache.org", solrDefaultMirror}
+
+var solrArchiveParamRegex = regexp.MustCompile(`\{SOLR_ARCHIVE(?::([0-9.]+))?\}`)
+var sha512HexRegex = regexp.MustCompile(`^[a-fA-F0-9]{128}$`)
+
+// solrArchiveVersions returns every solr version the service needs an archive for.
+func (s *Service) solrArchiveVersions() []string {
+	out := make([]string, 0)
+	if s.Version != "" {
+		out = append(out, s.Version)
+	}
+	for _, m := range solrArchiveParamRegex.FindAllStringSubmatch(s.PreInstallCmd+s.PostInstallCmd, -1) {
+		if m[1] == "" {
+			continue
+		}
+		hasVersion := false
+		for _, v := range out {
+			if v == m[1] {
+				hasVersion = true
+				break
+			}
+		}
+		if !hasVersion {
+			out = append(out, m[1])
+		}
+	}
+	return out
+}
+
+// solrInjectArchiveParams replaces {SOLR_ARCHIVE} and {SOLR_ARCHIVE:<version>} with archive paths.
+func (s *Service) solrInjectArchiveParams(cmd string) string {
+	return solrArchiveParamRegex.ReplaceAllStringFunc(cmd, func(m string) string {
+		version := solrArchiveParamRegex.FindStringSubmatch(m)[1]
+		if version == "" {
+			version = s.Version
+		}
+		return solrArchivePath(version)
+	})
+}
+
+// solrFetchArchives makes sure every solr archive needed by the service is available and verified.
+func (s *Service) solrFetchArchives() error {
+	for _, version := range s.solrArchiveVersions() {
+		if err := solrFetchArchive(version); err != nil {
+			return errors.WithMessage(err, s.DisplayName())
+		}
+	}
+	return nil
+}
+
+// solrArchiveName returns the file name of the solr release archive for given version.
+func solrArchiveName(version string) string {
+	return fmt.Sprintf("solr-%s.tgz", version)
+}
+
+// solrArchivePath returns the path to the solr archive for given version.
+func solrArchivePath(version string) string {
+	config, err := LoadConfig()
+	if err != nil {
+		output.Warn(err.Error())
+	}
+	if config.SolrArchiveDir != "" {
+		localPath := filepath.Join(resolveUserPath(config.SolrArchiveDir), solrArchiveName(version))
+		if _, err := os.Stat(localPath); err == nil {
+			return localPath
+		}
+	}
+	return filepath.Join(GetDir(BottleDir), solrArchiveName(version))
+}
+
+// solrArchiveURL returns the url of the solr release archive on given mirror.
+func solrArchiveURL(mirror string, version string) string {
+	mirror = strings.TrimRight(mirror, "/")
+	major, _ := strconv.Atoi(strings.Split(version, ".")[0])
+	// solr moved out of the lucene project with version 9
+	if major >= 9 {
+		return fmt.Sprintf("%s/solr/solr/%s/%s", mirror, version, solrArchiveName(version))
+	}
+	return fmt.Sprintf("%s/lucene/solr/%s/%s", mirror, version, solrArchiveName(version))
+}
+
+// solrMirrors returns list of mirrors to try in order.
+func solrMirrors() []string {
+	config, err := LoadConfig()
+	if err != nil {
+		output.Warn(err.Error())
+	}
+	out := make([]string, 0)
+	if config.SolrMirror != "" && strings.TrimRight(config.SolrMirror, "/") != solrDefaultMirror {
+		out = append(out, config.SolrMirror)
+	}
+	return append(out, solrDefaultMirror)
+}
+
+// solrFetchArchive downloads and verifies the solr archive for given version, unless a verified copy is available.
+func solrFetchArchive(version string) error {
+	archivePath := solrArchivePath(version)
+	checksumPath := archivePath + ".sha512"
+	// local or cached archive
+	if _, err := os.Stat(archivePath); err == nil {
+		checksum, err := solrReadChecksumFile(checksumPath)
+		if err != nil {
+			if !errors.Is(err, os.ErrNotExist) {
+				return err
+			}
+			checksum, err = solrDownloadChecksum(version)
+			if err != nil {
+				config, configErr := LoadConfig()
+				if configErr != nil {
+					output.Warn(configErr.Error())
+				}
+				if !config.SolrSkipChecksum {
+					return errors.WithMessage(err, archivePath)
+				}
+				output.Warn(fmt.Sprintf("Unable to verify %s, no checksum available.", archivePath))
+				return nil
+			}
+		}
+		if err := verifySHA512(archivePath, checksum); err != nil {
+			// local archives are never replaced
+			if filepath.Dir(archivePath) != GetDir(BottleDir) {
+				return err
+			}
+		} else {
+			output.LogInfo(fmt.Sprintf("Use cached Solr archive %s.", archivePath))
+			return nil
+		}
+		output.Warn(fmt.Sprintf("Cached Solr archive %s failed verification, downloading again.", archivePath))
+	}
+	done := output.Duration(fmt.Sprintf("Download Solr %s.", version))
+	checksum, err := solrDownloadChecksum(version)
+	if err != nil {
+		return err
+	}
+	archivePath = filepath.Join(GetDir(BottleDir), solrArchiveName(version))
+	checksumPath = archivePath + ".sha512"
+	tmpPath := archivePath + ".download"
+	var downloadErr error
+	for _, mirror := range solrMirrors() {
+		downloadErr = downloadFile(solrArchiveURL(mirror, version), tmpPath)
+		if downloadErr != nil {
+			output.LogWarn(downloadErr.Error())
+			continue
+		}
+		if downloadErr = verifySHA512(tmpPath, checksum); downloadErr != nil {
+			output.LogWarn(downloadErr.Error())
+			continue
+		}
+		break
+	}
+	if downloadErr != nil {
+		os.Remove(tmpPath)
+		return downloadErr
+	}
+	if err := os.Rename(tmpPath, archivePath); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := ioutil.WriteFile(checksumPath, []byte(checksum+"  "+solrArchiveName(version)+"\n"), 0644); err != nil {
+		return errors.WithStack(err)
+	}
+	done()
+	return nil
+}
+
+// solrDownloadChecksum fetches the published SHA-512 checksum for given solr version from Apache.
+func solrDownloadChecksum(version string) (string, error) {
+	var lastErr error
+	for _, mirror := range solrChecksumMirrors {
+		resp, err := http.Get(solrArchiveURL(mirror, version) + ".sha512")
+		if err != nil {
+			lastErr = errors.WithStack(err)
+			continue
+		}
+		raw, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			lastErr = errors.WithStack(err)
+			continue
+		}
+		if resp.StatusCode != http.StatusOK {
+			lastErr = errors.WithStack(errors.WithMessage(ErrChecksumNotFound, solrArchiveName(version)))
+			continue
+		}
+		checksum := parseSHA512(string(raw))
+		if checksum == "" {
+			lastErr = errors.WithStack(errors.WithMessage(ErrChecksumNotFound, solrArchiveName(version)))
+			continue
+		}
+		return checksum, nil
+	}
+	return "", lastErr
+}
+
+func solrReadChecksumFile(path string) (string, error) {
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	checksum := parseSHA512(string(raw))
+	if checksum == "" {
+		return "", errors.WithStack(errors.WithMessage(ErrChecksumNotFound, path))
+	}
+	return checksum, nil
+}
+
+// parseSHA512 returns the first SHA-512 hex digest found in a checksum file.
+func parseSHA512(value string) string {
+	for _, field := range strings.Fields(value) {
+		field = strings.TrimPrefix(field, "*")
+		if sha512HexRegex.MatchString(field) {
+			return strings.ToLower(field)
+		}
+	}
+	return ""
+}
+
+// verifySHA512 checks that the file at given path has given SHA-512 checksum.
+func verifySHA512(path string, checksum string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer f.Close()
+	h := sha512.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return errors.WithStack(err)
+	}
+	if hex.EncodeToString(h.Sum(nil)) != strings.ToLower(checksum) {
+		return errors.WithStack(errors.WithMessage(ErrChecksumMismatch, filepath.Base(path)))
+	}
+	return nil
+}
+
+// downloadFile downloads given url to given path.
+func downloadFile(url string, path string) error {
+	resp, err := http.Get(url)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return errors.WithStack(errors.WithMessage(os.ErrNotExist, url))
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer f.Close()
+	if _, err := io.Copy(f, resp.Body); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
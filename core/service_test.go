@@ -0,0 +1,109 @@
+package core
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/def"
+)
+
+func TestServiceAppInstances(t *testing.T) {
+	setupNginxRoutesTest(t, "")
+	loadedServiceList = nil
+	t.Cleanup(func() {
+		loadedServiceList = nil
+	})
+	serviceList, err := LoadServiceList()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &Project{Name: "proj"}
+	for _, appType := range []string{"php:8.1", "golang:1.24"} {
+		paths := make(map[string]string)
+		ports := make(map[int]string)
+		for _, appName := range []string{"web", "admin"} {
+			service, err := serviceList.Match(appType)
+			if err != nil {
+				t.Fatal(err)
+			}
+			service.SetDefinition(p, &def.App{Name: appName, Type: appType})
+			port, err := service.Port()
+			if err != nil {
+				t.Fatal(err)
+			}
+			instancePaths := []string{service.ConfigPath(), service.PidPath(), service.SocketPath(), service.phpIniPath()}
+			for _, path := range instancePaths {
+				if otherApp, ok := paths[path]; ok {
+					t.Errorf("%s apps %s and %s share %s", appType, otherApp, appName, path)
+				}
+				paths[path] = appName
+			}
+			if otherApp, ok := ports[port]; ok {
+				t.Errorf("%s apps %s and %s share port %d", appType, otherApp, appName, port)
+			}
+			ports[port] = appName
+		}
+	}
+}
+
+func TestServicePHPUpstream(t *testing.T) {
+	setupNginxRoutesTest(t, "")
+	loadedServiceList = nil
+	t.Cleanup(func() {
+		loadedServiceList = nil
+	})
+	serviceList, err := LoadServiceList()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := LoadProject(filepath.Join(appsTestData, "php_upstream"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		app          string
+		protocol     string
+		socketFamily string
+		start        string
+		nginx        string
+	}{
+		{app: "fpm", protocol: "fastcgi", socketFamily: "unix", start: "php-fpm", nginx: "fastcgi_pass"},
+		{app: "misconfigured", protocol: "fastcgi", socketFamily: "unix", start: "php-fpm", nginx: "fastcgi_pass"},
+		{app: "server", protocol: "http", socketFamily: "tcp", start: "php -S", nginx: "proxy_pass"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.app, func(t *testing.T) {
+			var app *def.App
+			for _, d := range p.Apps {
+				if d.Name == tt.app {
+					app = d
+				}
+			}
+			if app == nil {
+				t.Fatalf("app %s not found", tt.app)
+			}
+			service, err := serviceList.MatchDef(app)
+			if err != nil {
+				t.Fatal(err)
+			}
+			service.SetDefinition(p, app)
+			if out := service.UpstreamProtocol(); out != tt.protocol {
+				t.Errorf("expected protocol %s, got %s", tt.protocol, out)
+			}
+			if out := service.UpstreamSocketFamily(); out != tt.socketFamily {
+				t.Errorf("expected socket family %s, got %s", tt.socketFamily, out)
+			}
+			if out := service.injectCommandParams(service.startCmd()); !strings.Contains(out, tt.start) {
+				t.Errorf("expected start command to run %s, got %s", tt.start, out)
+			}
+			out, err := p.GenerateNginxApp(app)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(out, tt.nginx) {
+				t.Errorf("expected nginx config to use %s\n%s", tt.nginx, out)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package core
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/def"
+)
+
+const varnishVCLVersion = "4.0"
+
+// varnishVCLStrip matches the parts of a Platform.sh VCL that pbrew generates itself.
+var varnishVCLStrip = regexp.MustCompile(`(?m)^\s*(vcl\s+[0-9.]+|import\s+directors)\s*;\s*$`)
+
+// IsVarnish returns true if service is varnish.
+func (s *Service) IsVarnish() bool {
+	return strings.HasPrefix(s.BrewAppName(), "varnish")
+}
+
+// varnishDefinition returns the service definition of the varnish service.
+func (s *Service) varnishDefinition() (def.Service, bool) {
+	switch d := s.definition.(type) {
+	case *def.Service:
+		{
+			if d == nil {
+				return def.Service{}, false
+			}
+			return *d, true
+		}
+	case def.Service:
+		{
+			return d, true
+		}
+	}
+	return def.Service{}, false
+}
+
+// varnishRelationshipNames returns the relationship names of given varnish service in a stable order.
+func varnishRelationshipNames(d def.Service) []string {
+	out := make([]string, 0)
+	for name := range d.Relationships {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// varnishBackend returns the app behind given varnish service, the target of its first relationship.
+func (p *Project) varnishBackend(d def.Service) interface{} {
+	for _, name := range varnishRelationshipNames(d) {
+		if backend := p.MatchRelationshipToService(d.Relationships[name]); backend != nil {
+			return backend
+		}
+	}
+	return nil
+}
+
+// varnishVCL returns the VCL for the varnish service. Like on Platform.sh every relationship becomes a
+// director named after it (e.g. application.backend()), its backend is the app's local upstream port.
+func (s *Service) varnishVCL() string {
+	d, ok := s.varnishDefinition()
+	if !ok || s.project == nil {
+		return ""
+	}
+	out := fmt.Sprintf("vcl %s;\n\nimport directors;\n\n", varnishVCLVersion)
+	names := make([]string, 0)
+	for _, name := range varnishRelationshipNames(d) {
+		port := s.project.GetUpstreamPort(s.project.MatchRelationshipToService(d.Relationships[name]))
+		if port == 0 {
+			continue
+		}
+		out += fmt.Sprintf("backend %s_1 {\n    .host = \"127.0.0.1\";\n    .port = \"%d\";\n}\n\n", name, port)
+		names = append(names, name)
+	}
+	out += "sub vcl_init {\n"
+	for _, name := range names {
+		out += fmt.Sprintf("    new %s = directors.round_robin();\n    %s.add_backend(%s_1);\n", name, name, name)
+	}
+	out += "}\n\n"
+	vcl, _ := d.Configuration["vcl"].(string)
+	if strings.TrimSpace(vcl) == "" && len(names) > 0 {
+		vcl = fmt.Sprintf("sub vcl_recv {\n    set req.backend_hint = %s.backend();\n}\n", names[0])
+	}
+	return out + varnishVCLStrip.ReplaceAllString(vcl, "")
+}
+
+// varnishConfigParams returns the config template parameters for varnish.
+func (s *Service) varnishConfigParams() map[string]interface{} {
+	return map[string]interface{}{"VCL": s.varnishVCL()}
+}
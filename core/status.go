@@ -16,6 +16,9 @@ type ServiceStatus struct {
 	Ports       []int    `json:"ports"`
 	Projects    []string `json:"projects"`
 	Status      string   `json:"status"`
+	Version     string   `json:"version,omitempty"`
+	// RequestedVersion is the version requested by a project definition, only set for project status.
+	RequestedVersion string `json:"requested_version,omitempty"`
 }
 
 // GetServiceStatuses returns status of all services.
@@ -116,6 +119,7 @@ func GetServiceStatuses() ([]ServiceStatus, error) {
 			Ports:       ports,
 			Status:      status,
 			Projects:    projects,
+			Version:     service.ActualVersion(),
 		})
 	}
 	sort.Slice(out, func(i int, j int) bool {
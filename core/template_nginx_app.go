@@ -10,21 +10,39 @@ import (
 	"gitlab.com/contextualcode/platform_cc/v2/pkg/def"
 )
 
+const nginxAppProxyTemplateFile = "conf/nginx_app_proxy.conf.tmpl"
+
 var nginxAppTemplateFiles = map[string]string{
-	"php": "conf/nginx_app_php.conf.tmpl",
+	"php":    "conf/nginx_app_php.conf.tmpl",
+	"golang": nginxAppProxyTemplateFile,
 }
 
 type nginxAppTemplate struct {
 	Port      int
+	Upstream  string
 	Locations []nginxAppLocationTemplate
 }
 
 type nginxAppLocationTemplate struct {
-	Path     string
-	Root     string
-	Passthru string
-	Socket   string
-	Rules    []nginxAppLocationTemplate
+	Path             string
+	Root             string
+	Static           bool
+	Passthru         string
+	PassthruUpstream bool
+	Socket           string
+	Upstream         string
+	Rules            []nginxAppLocationTemplate
+}
+
+// nginxUpstream returns the nginx address for given upstream address and protocol.
+func nginxUpstream(address string, protocol string) string {
+	if !strings.HasPrefix(address, "/") {
+		return address
+	}
+	if protocol == "http" {
+		return "unix:" + address + ":"
+	}
+	return "unix:" + address
 }
 
 func (p *Project) buildNginxAppTemplate(app *def.App) (nginxAppTemplate, error) {
@@ -58,18 +76,23 @@ func (p *Project) buildNginxAppTemplate(app *def.App) (nginxAppTemplate, error)
 				Root:     ruleRoot,
 				Passthru: rule.Passthru.GetString(),
 				Socket:   service.UpstreamSocketPath(),
+				Upstream: nginxUpstream(service.UpstreamAddress(), service.UpstreamProtocol()),
 			})
 		}
 		locations = append(locations, nginxAppLocationTemplate{
-			Path:     path,
-			Root:     root,
-			Passthru: location.Passthru.GetString(),
-			Socket:   service.UpstreamSocketPath(),
-			Rules:    rules,
+			Path:             path,
+			Root:             root,
+			Static:           location.Root != "",
+			Passthru:         location.Passthru.GetString(),
+			PassthruUpstream: location.Passthru.GetBool() || location.Passthru.IsString(),
+			Socket:           service.UpstreamSocketPath(),
+			Upstream:         nginxUpstream(service.UpstreamAddress(), service.UpstreamProtocol()),
+			Rules:            rules,
 		})
 	}
 	return nginxAppTemplate{
 		Port:      p.GetUpstreamPort(app),
+		Upstream:  nginxUpstream(service.UpstreamAddress(), service.UpstreamProtocol()),
 		Locations: locations,
 	}, nil
 }
@@ -77,6 +100,10 @@ func (p *Project) buildNginxAppTemplate(app *def.App) (nginxAppTemplate, error)
 // GenerateNginxApp generates nginx config for given application.
 func (p *Project) GenerateNginxApp(app *def.App) (string, error) {
 	templatePath := nginxAppTemplateFiles[app.GetTypeName()]
+	// applications that serve http themselves are proxied to like go applications
+	if app.GetTypeName() == "php" && appServesHTTP(app) {
+		templatePath = nginxAppProxyTemplateFile
+	}
 	if templatePath == "" {
 		return "", errors.WithStack(errors.WithMessage(ErrTemplateNotFound, app.GetTypeName()))
 	}
@@ -5,38 +5,177 @@ import (
 	"fmt"
 	"net/url"
 	"path/filepath"
+	"regexp"
+	"sort"
 	"strings"
 	"text/template"
 
+	"gitlab.com/contextualcode/platform_cc/v2/pkg/def"
 	"gitlab.com/contextualcode/platform_cc/v2/pkg/output"
 
 	"github.com/pkg/errors"
 )
 
 const nginxRouteTemplateFile = "conf/nginx_routes.conf.tmpl"
+const nginxHSTSMaxAge = 31536000
 
 type nginxRouteTemplate struct {
 	ProjectName string
 	Hosts       []nginxRouteHostTemplate
+	Protocols   NginxProtocols
+	AltSvc      string
 }
 
 type nginxRouteHostTemplate struct {
-	Host      string
-	PortHTTP  int
-	PortHTTPS int
-	Locations []nginxRouteLocationTemplate
-	ErrorLog  string
-	AccessLog string
-	DataDir   string
+	Host          string
+	ServerName    string
+	Scheme        string
+	Port          int
+	PortHTTPS     int
+	RedirectHTTPS bool
+	Locations     []nginxRouteLocationTemplate
+	Redirects     []nginxRouteRedirectTemplate
+	ErrorLog      string
+	AccessLog     string
+	DataDir       string
 }
 
 type nginxRouteLocationTemplate struct {
 	Host         string
 	Original     string
+	ID           string
 	Path         string
+	PathRegex    string
 	Type         string
 	UpstreamPort int
+	App          string
+	Route        string
 	To           string
+	SSI          bool
+	HSTS         string
+}
+
+type nginxRouteRedirectTemplate struct {
+	Location string
+	To       string
+	Code     int
+	Expires  string
+	HSTS     string
+}
+
+// nginxBackreferenceRegex matches Platform.sh style \1 backreferences in redirect targets.
+var nginxBackreferenceRegex = regexp.MustCompile(`\\([0-9])`)
+
+// nginxServerName returns the nginx server_name for given host.
+// Wildcards that aren't a leading "*." (internal hosts like *-project-default.localtest.me) become a regex.
+func nginxServerName(host string) string {
+	if strings.HasPrefix(host, "*") && !strings.HasPrefix(host, "*.") {
+		return fmt.Sprintf("\"~^[^.]+%s$\"", regexp.QuoteMeta(strings.TrimPrefix(host, "*")))
+	}
+	return host
+}
+
+// nginxEscape escapes given value for use in a double quoted nginx string.
+func nginxEscape(value string) string {
+	return strings.ReplaceAll(value, "\"", "\\\"")
+}
+
+// nginxRouteTo returns given redirect target with {default} expanded for given route.
+// Targets of internal routes that point at one of the project's hosts are converted to the internal host.
+func (p *Project) nginxRouteTo(route def.Route, to string) string {
+	to = strings.ReplaceAll(to, "{default}", "__PID__.default")
+	routeURL, err := url.Parse(route.Path)
+	if err != nil {
+		return ProjectDefaultHostName(p, to)
+	}
+	originalURL, err := url.Parse(route.OriginalURL)
+	if err != nil {
+		return ProjectDefaultHostName(p, to)
+	}
+	toURL, err := url.Parse(to)
+	if err != nil || toURL.Host == "" || routeURL.Host == originalURL.Host {
+		return ProjectDefaultHostName(p, to)
+	}
+	internalSuffix := strings.TrimPrefix(routeURL.Host, strings.ReplaceAll(originalURL.Host, ".", "-"))
+	for _, r := range p.Routes {
+		rURL, err := url.Parse(r.OriginalURL)
+		if err == nil && rURL.Host == toURL.Host {
+			toURL.Host = strings.ReplaceAll(toURL.Host, ".", "-") + internalSuffix
+			break
+		}
+	}
+	return ProjectDefaultHostName(p, toURL.String())
+}
+
+// nginxRouteHSTS returns the Strict-Transport-Security header value from tls.strict_transport_security of given route.
+func (p *Project) nginxRouteHSTS(raw map[string]interface{}, route def.Route) string {
+	hsts := yamlLookup(raw[route.OriginalURL], "tls", "strict_transport_security")
+	if !yamlLookupBool(hsts, "enabled") {
+		return ""
+	}
+	out := fmt.Sprintf("max-age=%d", nginxHSTSMaxAge)
+	if yamlLookupBool(hsts, "include_subdomains") {
+		out += "; includeSubDomains"
+	}
+	if yamlLookupBool(hsts, "preload") {
+		out += "; preload"
+	}
+	return out
+}
+
+// nginxRouteRedirects returns the partial redirects of given route from redirects.paths.
+func (p *Project) nginxRouteRedirects(route def.Route, hsts string) []nginxRouteRedirectTemplate {
+	out := make([]nginxRouteRedirectTemplate, 0)
+	paths := make([]string, 0)
+	for path := range route.Redirects.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		redirect := route.Redirects.Paths[path]
+		to := redirect.To
+		if redirect.Regexp.Get() {
+			// nginx uses $1 for captures, rewritten before the target is parsed as an url
+			to = nginxBackreferenceRegex.ReplaceAllString(to, "$$$1")
+		}
+		to = p.nginxRouteTo(route, to)
+		expires := redirect.Expires
+		if expires == "" {
+			expires = route.Redirects.Expires
+		}
+		if expires == "-1" {
+			expires = ""
+		}
+		location := fmt.Sprintf("= \"%s\"", nginxEscape(path))
+		switch {
+		case redirect.Regexp.Get():
+			{
+				location = fmt.Sprintf("~ \"%s\"", nginxEscape(path))
+				break
+			}
+		case redirect.Prefix.Get():
+			{
+				location = fmt.Sprintf("~ \"^%s(?:/(.*))?$\"", nginxEscape(regexp.QuoteMeta(strings.TrimRight(path, "/"))))
+				if redirect.AppendSuffix.Get() {
+					to = strings.TrimRight(to, "/") + "/$1"
+				}
+				to += "$is_args$args"
+				break
+			}
+		default:
+			{
+				to += "$is_args$args"
+			}
+		}
+		out = append(out, nginxRouteRedirectTemplate{
+			Location: location,
+			To:       nginxEscape(to),
+			Code:     redirect.Code,
+			Expires:  expires,
+			HSTS:     hsts,
+		})
+	}
+	return out
 }
 
 func (p *Project) buildNginxRouteTemplate() nginxRouteTemplate {
@@ -45,65 +184,126 @@ func (p *Project) buildNginxRouteTemplate() nginxRouteTemplate {
 		output.Warn(err.Error())
 		return nginxRouteTemplate{}
 	}
+	raw := p.routesYamlRaw()
 	hostTemplates := make([]nginxRouteHostTemplate, 0)
 	for _, hostName := range GetHostNames(p.Routes) {
-		locationTemplates := make([]nginxRouteLocationTemplate, 0)
+		routesByScheme := map[string][]def.Route{"http": {}, "https": {}}
 		for _, route := range GetRoutesForHostName(hostName, p.Routes) {
-			if route.Path != "" && route.Path[0] == '.' {
-				continue
-			}
 			parsedRouteURL, err := url.Parse(route.Path)
 			if err != nil {
+				output.LogWarn(err.Error())
 				continue
 			}
-			parsedOriginalURL, err := url.Parse(route.OriginalURL)
-			if err != nil {
-				continue
+			scheme := parsedRouteURL.Scheme
+			if scheme != "https" {
+				scheme = "http"
+			}
+			routesByScheme[scheme] = append(routesByScheme[scheme], route)
+		}
+		for _, scheme := range []string{"http", "https"} {
+			routes := routesByScheme[scheme]
+			redirectHTTPS := false
+			if len(routes) == 0 {
+				// routes only defined for https are redirected from http, routes only
+				// defined for http are also served over https
+				if scheme == "http" {
+					redirectHTTPS = true
+				} else {
+					routes = routesByScheme["http"]
+				}
 			}
-			upstreamPort := 0
-			if route.Type == "upstream" {
-				service := p.MatchRelationshipToService(route.Upstream)
-				if service != nil {
-					upstreamPort = p.GetUpstreamPort(service)
+			locationTemplates := make([]nginxRouteLocationTemplate, 0)
+			redirectTemplates := make([]nginxRouteRedirectTemplate, 0)
+			for _, route := range routes {
+				parsedRouteURL, err := url.Parse(route.Path)
+				if err != nil {
+					continue
 				}
-				if upstreamPort == 0 {
+				parsedOriginalURL, err := url.Parse(route.OriginalURL)
+				if err != nil {
 					continue
 				}
-			}
-			path := strings.TrimRight(parsedRouteURL.Path, "/")
-			hasPath := false
-			for _, l := range locationTemplates {
-				if l.Path == path {
-					hasPath = true
-					break
+				upstreamPort := 0
+				app := ""
+				if route.Type == "upstream" {
+					app = strings.Split(route.Upstream, ":")[0]
+					service := p.MatchRelationshipToService(route.Upstream)
+					if service != nil {
+						upstreamPort = p.GetUpstreamPort(service)
+					}
+					if upstreamPort == 0 {
+						continue
+					}
+				}
+				// nginx allows one location per path in a server block
+				path := strings.TrimRight(parsedRouteURL.Path, "/")
+				hasPath := false
+				for _, l := range locationTemplates {
+					if l.Path == path {
+						output.Warn(fmt.Sprintf(
+							"Route %s is ignored, route %s already uses the same path.",
+							ProjectDefaultHostName(p, route.OriginalURL), l.Route,
+						))
+						hasPath = true
+						break
+					}
+				}
+				if hasPath {
+					continue
 				}
+				hsts := ""
+				if scheme == "https" {
+					hsts = p.nginxRouteHSTS(raw, route)
+				}
+				to := p.nginxRouteTo(route, route.To)
+				if route.Type == "redirect" {
+					to = nginxEscape(strings.TrimRight(to, "/") + "/$1$is_args$args")
+				}
+				locationTemplates = append(locationTemplates, nginxRouteLocationTemplate{
+					Host:         ProjectDefaultHostName(p, hostName),
+					Original:     ProjectDefaultHostName(p, parsedOriginalURL.Host),
+					ID:           route.ID,
+					Path:         path,
+					PathRegex:    nginxEscape(regexp.QuoteMeta(path)),
+					Type:         route.Type,
+					UpstreamPort: upstreamPort,
+					App:          nginxEscape(app),
+					Route:        nginxEscape(ProjectDefaultHostName(p, route.OriginalURL)),
+					To:           to,
+					SSI:          route.SSI.Enabled.Get(),
+					HSTS:         hsts,
+				})
+				redirectTemplates = append(redirectTemplates, p.nginxRouteRedirects(route, hsts)...)
 			}
-			if hasPath {
-				continue
+			port := config.RouterHTTP
+			if scheme == "https" {
+				port = config.RouterHTTPS
 			}
-			locationTemplates = append(locationTemplates, nginxRouteLocationTemplate{
-				Host:         ProjectDefaultHostName(p, hostName),
-				Original:     parsedOriginalURL.Host,
-				Path:         path,
-				Type:         route.Type,
-				UpstreamPort: upstreamPort,
-				To:           route.To,
+			hostTemplates = append(hostTemplates, nginxRouteHostTemplate{
+				Host:          ProjectDefaultHostName(p, hostName),
+				ServerName:    nginxServerName(ProjectDefaultHostName(p, hostName)),
+				Scheme:        scheme,
+				Port:          port,
+				PortHTTPS:     config.RouterHTTPS,
+				RedirectHTTPS: redirectHTTPS,
+				Locations:     locationTemplates,
+				Redirects:     redirectTemplates,
+				ErrorLog:      filepath.Join(GetDir(LogDir), fmt.Sprintf("nginx_error_%s.log", p.Name)),
+				AccessLog:     NginxAccessLogPath(p),
+				DataDir:       filepath.Join(GetDir(DataDir), "nginx"),
 			})
 		}
-
-		hostTemplates = append(hostTemplates, nginxRouteHostTemplate{
-			Host:      ProjectDefaultHostName(p, hostName),
-			PortHTTP:  config.RouterHTTP,
-			PortHTTPS: config.RouterHTTPS,
-			Locations: locationTemplates,
-			ErrorLog:  filepath.Join(GetDir(LogDir), fmt.Sprintf("nginx_error_%s.log", p.Name)),
-			AccessLog: filepath.Join(GetDir(LogDir), fmt.Sprintf("nginx_access_%s.log", p.Name)),
-			DataDir:   filepath.Join(GetDir(DataDir), "nginx"),
-		})
+	}
+	protocols := GetNginxProtocols()
+	altSvc := ""
+	if protocols.HTTP3 {
+		altSvc = fmt.Sprintf("h3=\":%d\"; ma=86400", config.RouterHTTPS)
 	}
 	return nginxRouteTemplate{
 		ProjectName: p.Name,
 		Hosts:       hostTemplates,
+		Protocols:   protocols,
+		AltSvc:      altSvc,
 	}
 }
 
@@ -0,0 +1,118 @@
+package core
+
+import (
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var updateGolden = flag.Bool("update", false, "update golden files")
+
+const nginxRoutesTestData = "testdata/nginx_routes"
+const nginxRoutesGoldenFile = "routes.conf.golden"
+
+// setupNginxRoutesTest points pbrew's directories at a temporary user dir so ports, domains and logs
+// don't depend on the machine, and fakes the output of nginx -V.
+func setupNginxRoutesTest(t *testing.T, buildInfo string) string {
+	userDir := t.TempDir()
+	appPath, err := filepath.Abs("..")
+	if err != nil {
+		t.Fatal(err)
+	}
+	prevDirectories := make(map[int]string)
+	for key, dir := range appDirectories {
+		prevDirectories[key] = dir
+		appDirectories[key] = filepath.Join(userDir, filepath.Base(dir))
+	}
+	appDirectories[UserDir] = userDir
+	appDirectories[AppDir] = appPath
+	prevConfig := loadedConfig
+	config := DefaultConfig()
+	loadedConfig = &config
+	nginxBuildInfoCache = &buildInfo
+	t.Cleanup(func() {
+		appDirectories = prevDirectories
+		loadedConfig = prevConfig
+		nginxBuildInfoCache = nil
+	})
+	if err := InitDirs(); err != nil {
+		t.Fatal(err)
+	}
+	return userDir
+}
+
+func TestGenerateNginxRoutes(t *testing.T) {
+	tests := []struct {
+		name      string
+		buildInfo string
+	}{
+		{name: "upstream"},
+		{name: "redirect"},
+		{name: "partial_redirects"},
+		{name: "hsts"},
+		{name: "http_to_https"},
+		{name: "http2", buildInfo: nginxTestBuildInfo},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userDir := setupNginxRoutesTest(t, tt.buildInfo)
+			if tt.buildInfo != "" {
+				loadedConfig.RouterHTTP3 = true
+			}
+			projPath := filepath.Join(nginxRoutesTestData, tt.name)
+			p, err := LoadProject(projPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			out, err := p.GenerateNginxRoutes()
+			if err != nil {
+				t.Fatal(err)
+			}
+			out = normalizeNginxConfig(strings.ReplaceAll(out, userDir, "{USER_DIR}"))
+			goldenPath := filepath.Join(projPath, nginxRoutesGoldenFile)
+			if *updateGolden {
+				if err := ioutil.WriteFile(goldenPath, []byte(out), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			expected, err := ioutil.ReadFile(goldenPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if out != string(expected) {
+				t.Errorf("generated config doesn't match %s, run go test ./core -update to update it\n%s", goldenPath, out)
+			}
+		})
+	}
+}
+
+func TestNginxRouteRedirectsBackreferences(t *testing.T) {
+	tests := []struct {
+		to       string
+		expected string
+	}{
+		{to: "https://example.com/products/\\1", expected: "https://example.com/products/$1"},
+		{to: "https://example.com/\\1/\\2", expected: "https://example.com/$1/$2"},
+		{to: "https://example.com/$1", expected: "https://example.com/$1"},
+		{to: "https://example.com/static", expected: "https://example.com/static"},
+	}
+	for _, tt := range tests {
+		if out := nginxBackreferenceRegex.ReplaceAllString(tt.to, "$$$1"); out != tt.expected {
+			t.Errorf("expected %s for %s, got %s", tt.expected, tt.to, out)
+		}
+	}
+}
+
+// normalizeNginxConfig drops the blank lines and trailing whitespace the template leaves behind.
+func normalizeNginxConfig(config string) string {
+	out := make([]string, 0)
+	for _, line := range strings.Split(config, "\n") {
+		line = strings.TrimRight(line, " \t")
+		if line != "" {
+			out = append(out, line)
+		}
+	}
+	return strings.Join(out, "\n") + "\n"
+}